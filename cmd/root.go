@@ -4,13 +4,21 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 
 	"SecureJS/config"
 	"SecureJS/internal/analyze"
-	"SecureJS/internal/crawler"
+	"SecureJS/internal/auth"
+	"SecureJS/internal/local"
 	"SecureJS/internal/matcher"
 	"SecureJS/internal/output"
 	"SecureJS/internal/parser"
+	"SecureJS/internal/pipeline"
+	"SecureJS/internal/scope"
+	"SecureJS/internal/traffic"
 	"SecureJS/internal/utils"
 
 	"github.com/spf13/cobra"
@@ -19,13 +27,25 @@ import (
 var (
 	singleURL  string
 	listFile   string
+	paths      []string
+	harFiles   []string
+	burpFiles  []string
 	threads    int
+	depth      int
+	maxPages   int
 	configPath string
 	outputFile string
 	browserPath string
 	customHeaders []string
 	proxy string
+	scopeHosts []string
+	scopeInclude []string
+	scopeExclude []string
+	sameDomain bool
+	cookieFile string
 	ai string
+	aiFilter string
+	aiLang string
 	ARK_API_KEY string
 	Model_ENDPOINT_ID string
 )
@@ -33,15 +53,27 @@ var (
 func init() {
 	rootCmd.Flags().StringVarP(&singleURL, "url", "u", "", "Single target URL to scan (e.g. https://example.com)")
 	rootCmd.Flags().StringVarP(&listFile, "list", "l", "", "File containing target URLs (one per line)")
+	rootCmd.Flags().StringArrayVar(&paths, "path", nil, "Scan a local file or directory instead of crawling, including .zip/.jar/.war/.tar.gz archives and .map files (repeatable)")
+	rootCmd.Flags().StringArrayVar(&harFiles, "har", nil, "Scan requests and responses recorded in a HAR file instead of crawling (repeatable)")
+	rootCmd.Flags().StringArrayVar(&burpFiles, "burp", nil, "Scan requests and responses from a Burp Suite XML export instead of crawling (repeatable)")
 	rootCmd.Flags().IntVarP(&threads, "threads", "t", 20, "Number of concurrent threads for scanning")
+	rootCmd.Flags().IntVarP(&depth, "depth", "d", 1, "Crawl depth. 1 only crawls the seed URLs, higher values also crawl newly found pages and JS files")
+	rootCmd.Flags().IntVar(&maxPages, "max-pages", 500, "Maximum number of pages/JS files to crawl per run (0 = unlimited)")
 	rootCmd.Flags().StringVarP(&configPath, "config", "c", "config/config.yaml", "Path to config file (e.g. config.yaml)")
-	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file (supports .txt, .csv, .json)")
+	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file (supports .txt, .csv, .json, .jsonl, .sarif, .html; - streams JSON Lines to stdout)")
 	rootCmd.Flags().StringVarP(&browserPath, "browser", "b", "", "Path to Chrome/Chromium executable (optional). If not set, will use Rod's default.")
 	rootCmd.Flags().StringArrayVarP(&customHeaders, "header", "H", nil, "Add custom request headers. (e.g. -H 'Key: Value')")
 	rootCmd.Flags().StringVarP(&proxy, "proxy", "p", "", "Proxy to use (e.g. http://127.0.0.1:8080)")
+	rootCmd.Flags().StringArrayVar(&scopeHosts, "scope", nil, "Add an in-scope host, wildcard subdomain or CIDR, '*' allows any host (e.g. --scope '*.example.com' --scope 10.0.0.0/8)")
+	rootCmd.Flags().StringArrayVar(&scopeInclude, "include", nil, "Only crawl/scan links matching this regex (repeatable)")
+	rootCmd.Flags().StringArrayVar(&scopeExclude, "exclude", nil, "Never crawl/scan links matching this regex (repeatable)")
+	rootCmd.Flags().BoolVar(&sameDomain, "same-domain", false, "Only crawl/scan links on the same registrable domain as the target URLs")
+	rootCmd.Flags().StringVar(&cookieFile, "cookie-file", "", "Import cookies from a Netscape cookies.txt or JSON cookie file (overrides auth.cookie_file in config)")
 	rootCmd.Flags().StringVarP(&ai, "ai", "a", "false", "true/false. Enable AI Analytics. If not set, will use false")
-	rootCmd.Flags().StringVarP(&Model_ENDPOINT_ID, "id", "i", "", "YOUR_ENDPOINT_ID")
-	rootCmd.Flags().StringVarP(&ARK_API_KEY, "key", "k", "", "ARK_API_KEY")
+	rootCmd.Flags().StringVar(&aiFilter, "ai-filter", "all", "Filter findings by AI verdict: all, true-positive (drop findings judged false positive) or false-positive")
+	rootCmd.Flags().StringVar(&aiLang, "ai-lang", "", "Language of the AI prompt and verdict reasoning: en, zh or a language from ai.prompt_templates (overrides ai.language in config)")
+	rootCmd.Flags().StringVarP(&Model_ENDPOINT_ID, "id", "i", "", "Model name for the AI provider, or the endpoint ID for Ark (overrides ai.model in config)")
+	rootCmd.Flags().StringVarP(&ARK_API_KEY, "key", "k", "", "API key for the AI provider (overrides the env var set by ai.api_key_env)")
 }
 
 var rootCmd = &cobra.Command{
@@ -60,59 +92,142 @@ var rootCmd = &cobra.Command{
 			if err != nil {
 				log.Fatalf("[!] Failed to read URLs from file %s: %v\n", listFile, err)
 			}
-		} else {
-			fmt.Println("[!] Please provide either a single -u <URL> or a -l <file> containing URLs.")
+		} else if len(paths) == 0 && len(harFiles) == 0 && len(burpFiles) == 0 {
+			fmt.Println("[!] Please provide either a single -u <URL>, a -l <file> containing URLs, a --path <file or directory>, or a --har/--burp traffic file.")
 			os.Exit(1)
 		}
 
-		// 2) 爬取链接（这里两个思路）2.1 && 2.2
-		uniqueLinks := make(map[string]struct{}) // 使用 map 来跟踪已添加的链接，实现去重
-		var toParse []string // 所有捕获的链接放入 toParse
+		aiEnabled := ai == "true"
+		if !slices.Contains(analyze.FilterModes, aiFilter) {
+			log.Fatalf("[!] Invalid --ai-filter '%s', valid values: %s\n", aiFilter, strings.Join(analyze.FilterModes, ", "))
+		}
+		if aiFilter != "all" && !aiEnabled {
+			log.Fatalf("[!] --ai-filter requires AI analysis (-a true)\n")
+		}
 
-		// 	2.1 收集加载某个目标 url 后（使用无头浏览器），默认加载的所有其他链接（js等）并放入 toParse
-		err := crawler.CollectLinks(urls, threads, uniqueLinks, &toParse, browserPath, customHeaders, proxy)
+		// 2) 加载 config.yaml 中的扫描范围、认证与敏感信息正则匹配规则，命令行参数追加到配置中的扫描范围
+		cfg, err := config.LoadConfig(configPath)
 		if err != nil {
-			log.Fatalf("[!] Error collecting links: %v", err)
+			log.Fatalf("[!] Failed to load config: %v\n", err)
+		}
+		cfg.Scope.Hosts = append(cfg.Scope.Hosts, scopeHosts...)
+		cfg.Scope.Include = append(cfg.Scope.Include, scopeInclude...)
+		cfg.Scope.Exclude = append(cfg.Scope.Exclude, scopeExclude...)
+		if sameDomain {
+			cfg.Scope.SameDomain = true
+		}
+		sc, err := scope.New(cfg.Scope, urls)
+		if err != nil {
+			log.Fatalf("[!] Invalid scope: %v\n", err)
 		}
 
-		// 	2.2 收集加载某个目标 url 后其 body 中的链接（js等）并放入 toParse
-		err = crawler.CollectLinksFromBody(urls, threads, uniqueLinks, &toParse, customHeaders, proxy)
+		// 3) 编译匹配规则
+		m, err := matcher.New(cfg.Rules, cfg.Allowlist, *cfg.Context, sc)
 		if err != nil {
-			log.Fatalf("[!] Error collecting links from body: %v", err)
+			log.Fatalf("[!] Failed to compile rules: %v\n", err)
 		}
 
-		// for _, jsurl := range toParse {
-		// 	fmt.Println(jsurl)
-		// }
+		// 	启用 AI 分析时在扫描之前创建后端并加载提示词模板，配置有误时不必等到扫描结束才发现
+		var analyzer *analyze.Analyzer
+		if aiEnabled {
+			// 	-i 覆盖 ai.model，-k 覆盖从环境变量读取的 API Key，--ai-lang 覆盖 ai.language
+			if Model_ENDPOINT_ID != "" {
+				cfg.AI.Model = Model_ENDPOINT_ID
+			}
+			if aiLang != "" {
+				cfg.AI.Language = strings.ToLower(aiLang)
+			}
+			provider, err := analyze.NewProvider(*cfg.AI, ARK_API_KEY)
+			if err != nil {
+				log.Fatalf("[!] Failed to set up AI provider: %v\n", err)
+			}
+			// 	提示词模板可以引用本次扫描的目标
+			targets := append(append(append(append([]string{}, urls...), paths...), harFiles...), burpFiles...)
+			analyzer, err = analyze.New(provider, *cfg.AI, cfg.Rules, targets)
+			if err != nil {
+				log.Fatalf("[!] Failed to load AI prompt template: %v\n", err)
+			}
+		}
 
-		// 3) 对所有收集到的链接进行二次请求
-		parseResults, err := parser.ParseAll(toParse, threads, customHeaders, proxy)
-		if err != nil {
-			log.Fatalf("[!] Failed to parseAll: %v\n", err)
+		// 	--path 扫描本地文件和目录，--har/--burp 扫描导入的流量记录，都不启动浏览器；
+		// 	有目标 URL 时爬取、请求和匹配以流水线方式同时进行，每个响应匹配完后即释放
+		run := func(emit func(*matcher.MatchResult)) {
+			if len(paths) > 0 {
+				local.Run(paths, cfg.HTTP.MaxBodySize, m, emit)
+			}
+			if len(harFiles) > 0 || len(burpFiles) > 0 {
+				traffic.Run(harFiles, burpFiles, cfg.HTTP.MaxBodySize, m, emit)
+			}
+			if len(urls) == 0 {
+				return
+			}
+
+			// 	导入 cookie 文件或执行登录流程，得到的会话由无头浏览器和二次请求共用，失效时自动重新登录
+			if cookieFile != "" {
+				cfg.Auth.CookieFile = cookieFile
+			}
+			sess, err := auth.New(cfg.Auth, browserPath, proxy, customHeaders)
+			if err != nil {
+				log.Fatalf("[!] Failed to set up authentication: %v\n", err)
+			}
+			opts := pipeline.Options{
+				Threads:       threads,
+				Depth:         depth,
+				MaxPages:      maxPages,
+				BrowserPath:   browserPath,
+				CustomHeaders: customHeaders,
+				Proxy:         proxy,
+				Client:        parser.NewClient(*cfg.HTTP, customHeaders, proxy, sess, sc),
+				Scope:         sc,
+				Session:       sess,
+			}
+			pipeline.Run(urls, opts, m, emit)
 		}
 
-		// 4) 加载 config.yaml 中的敏感信息正则匹配规则
-		cfg, err := config.LoadConfig(configPath)
-		if err != nil {
-			log.Fatalf("[!] Failed to load config: %v\n", err)
+		// 	-o - 或 .jsonl：每条命中和每个请求失败的 URL 在匹配后立即输出，日志输出到 stderr；
+		// 	启用 AI 分析时需要完整的结果，分析完成后再一次性输出
+		if output.IsStream(outputFile) && !aiEnabled {
+			jw, closeStream, err := output.NewStream(outputFile)
+			if err != nil {
+				log.Fatalf("[!] Failed to open output: %v\n", err)
+			}
+			run(func(mr *matcher.MatchResult) {
+				if err := jw.Write(mr); err != nil {
+					log.Fatalf("[!] Failed to write results: %v\n", err)
+				}
+			})
+			if err := closeStream(); err != nil {
+				log.Fatalf("[!] Failed to close output: %v\n", err)
+			}
+			return
 		}
 
-		// 5) 对所有收集到的链接进行二次请求后的 body 中进行敏感信息的匹配
-		matchResults, err := matcher.MatchAll(cfg.Rules, parseResults)
-		if err != nil {
-			log.Fatalf("[!] Failed to matchAll: %v\n", err)
+		// 	其他输出格式需要完整的结果，只保留匹配结果，响应内容不会常驻内存
+		var (
+			mu           sync.Mutex
+			matchResults []*matcher.MatchResult
+		)
+		run(func(mr *matcher.MatchResult) {
+			mu.Lock()
+			matchResults = append(matchResults, mr)
+			mu.Unlock()
+		})
+		// 流水线中结果的顺序不固定，按 URL 排序后输出
+		sort.Slice(matchResults, func(i, j int) bool { return matchResults[i].URL < matchResults[j].URL })
+
+		// 4) AI 分析：每条命中的判断写入结果，随结果一起输出，并可以按判断过滤
+		if analyzer != nil {
+			if err := analyzer.Analyze(matchResults); err != nil {
+				log.Printf("[!] AI analysis failed, reporting findings without verdicts: %v", err)
+			}
+			matchResults = analyze.Filter(matchResults, aiFilter)
 		}
 
-		// 6) 输出
+		// 5) 输出
 		if outputFile == "" {
-			if ai == "true" {
-				resultString := analyze.FormatResultsToString(matchResults)
-				analyze.Analyze(resultString, ARK_API_KEY, Model_ENDPOINT_ID)
-			} else {
-				output.PrintResultsToConsole(matchResults)
-			}
+			output.PrintResultsToConsole(matchResults)
 		} else {
-			err := output.WriteResultsToFile(matchResults, outputFile)
+			err := output.WriteResultsToFile(matchResults, cfg.Rules, outputFile)
 			if err != nil {
 				log.Fatalf("[!] Failed to write results to file: %v\n", err)
 			}
@@ -3,9 +3,14 @@ module SecureJS
 go 1.23
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/go-rod/rod v0.116.2
+	github.com/klauspost/compress v1.18.0
 	github.com/spf13/cobra v1.8.1
 	github.com/volcengine/volcengine-go-sdk v1.0.181
+	github.com/ysmood/gson v0.7.3
+	golang.org/x/net v0.30.0
+	golang.org/x/text v0.19.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -18,7 +23,6 @@ require (
 	github.com/ysmood/fetchup v0.2.4 // indirect
 	github.com/ysmood/goob v0.4.0 // indirect
 	github.com/ysmood/got v0.40.0 // indirect
-	github.com/ysmood/gson v0.7.3 // indirect
 	github.com/ysmood/leakless v0.9.0 // indirect
 	gopkg.in/yaml.v2 v2.2.8 // indirect
 )
@@ -1,18 +1,26 @@
 package analyze
 
 import (
+	"SecureJS/config"
 	"SecureJS/internal/matcher"
 	"context"
 	"fmt"
-	"time"
-
-	"github.com/volcengine/volcengine-go-sdk/service/arkruntime"
-	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
-	"github.com/volcengine/volcengine-go-sdk/volcengine"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
 )
 
+// FormatResultsToString 将匹配结果格式化为发送给大模型的文本，每条命中按顺序编号为 [#1]、[#2]……，
+// 编号与 findings 返回的顺序一致，大模型按编号给出判断
 func FormatResultsToString(results []*matcher.MatchResult) string {
+	return formatResults(results, nil)
+}
+
+// formatResults 与 FormatResultsToString 相同，r 不为 nil 时 URL 和命中中的 secret 按 r 脱敏
+func formatResults(results []*matcher.MatchResult, r *redactor) string {
 	var resultString string
+	id := 0
 	for _, mr := range results {
 		if mr.Error != nil {
 			resultString += fmt.Sprintf("\n[!] Parse error on %s: %v\n", mr.URL, mr.Error)
@@ -21,61 +29,223 @@ func FormatResultsToString(results []*matcher.MatchResult) string {
 		if len(mr.Items) == 0 {
 			continue
 		}
-		resultString += fmt.Sprintf("\n[+] %s: found %d item(s)\n", mr.URL, len(mr.Items))
+		resultString += formatURL(r.text(mr.URL), len(mr.Items))
 		for _, item := range mr.Items {
-			resultString += fmt.Sprintf("    - Rule: %s, Matched: %s\n", item.RuleName, item.MatchedText)
+			id++
+			resultString += r.format(id, item)
+		}
+	}
+	return resultString
+}
+
+// formatURL 返回一个 URL 的命中列表的标题行
+func formatURL(url string, count int) string {
+	return fmt.Sprintf("\n[+] %s: found %d item(s)\n", url, count)
+}
+
+// formatFinding 返回编号为 id 的命中的文本，包括规则信息、说明和上下文
+func formatFinding(id int, item matcher.MatchItem) string {
+	var resultString string
+	resultString += fmt.Sprintf("    - [#%d] Rule: %s, Severity: %s, Confidence: %s", id, item.RuleName, item.Severity, item.Confidence)
+	if len(item.Tags) > 0 {
+		resultString += fmt.Sprintf(", Tags: %s", strings.Join(item.Tags, ","))
+	}
+	if item.Secret != "" && item.Secret != item.MatchedText {
+		resultString += fmt.Sprintf(", Secret: %s", item.Secret)
+	}
+	resultString += fmt.Sprintf(", Matched: %s", item.MatchedText)
+	if item.Location != "" {
+		resultString += fmt.Sprintf(", Location: %s", item.Location)
+	} else if item.Line > 0 {
+		resultString += fmt.Sprintf(", Line: %d, Column: %d", item.Line, item.Column)
+	}
+	resultString += "\n"
+	if item.Description != "" {
+		resultString += fmt.Sprintf("      Description: %s\n", item.Description)
+	}
+	if item.Context != "" {
+		resultString += "      Context:\n"
+		for _, line := range strings.Split(item.Context, "\n") {
+			resultString += "        | " + line + "\n"
 		}
 	}
 	return resultString
 }
 
-func Analyze(resultString string, key string, id string) {
-	var instructionString = `
-您是资深网络安全专家，擅长识别代码中的敏感信息泄露。请保持专业严谨，区分测试数据与实际风险。
-请按以下步骤处理输入内容（主要目标是从JS中寻找一些硬编码的”有具体数值的”敏感信息，不需要乱七八遭的代码如某token、key等值为+t.access_token+这样形式的）：
-1. 提取条目：识别所有符合 字段名 + 赋值符 + 值  结构的条目
-2. 过滤规则：
-（1）排除项：无明确值的字段（例如只包含一个AccessKeyId而没有具体的值）；公开/测试密钥；通用配置；没有具体的敏感信息数值（有的只是代码？乱糟糟的？）；
-3. 输出格式（严格遵循，不要多余的输出比如总结什么的，就只输出我下面三行内容）：
-URL：{文件URL}
-敏感信息：{字段名} = {值}
-分析：{风险说明，包含用途、泄露后果、是否生产环境}
-4. 如果此次分析整体并没有任何敏感信息，就直接表明 “无敏感信息”`
-
-    client := arkruntime.NewClientWithApiKey(
-        //通过 os.Getenv 从环境变量中获取 ARK_API_KEY
-        key,
-        //深度推理模型耗费时间会较长，请您设置较大的超时时间，避免超时导致任务失败。推荐30分钟以上
-        arkruntime.WithTimeout(30*time.Minute),
-    )
-    // 创建一个上下文，通常用于传递请求的上下文信息，如超时、取消等
-    ctx := context.Background()
-    // 构建聊天完成请求，设置请求的模型和消息内容
-    req := model.ChatCompletionRequest{
-        // 需要替换 <YOUR_ENDPOINT_ID> 为您的推理接入点 ID
-        Model: id,
-        Messages: []*model.ChatCompletionMessage{
-            {
-                // 消息的角色为用户
-                Role: model.ChatMessageRoleUser,
-                Content: &model.ChatCompletionMessageContent{
-                    StringValue: volcengine.String(instructionString + resultString),
-                },
-            },
-        },
-    }
-
-    // 发送聊天完成请求，并将结果存储在 resp 中，将可能出现的错误存储在 err 中
-    resp, err := client.CreateChatCompletion(ctx, req)
-    if err != nil {
-        // 若出现错误，打印错误信息并终止程序
-        fmt.Printf("standard chat error: %v\n", err)
-        return
-    }
-    // 检查是否触发深度推理，触发则打印思维链内容
-    // if resp.Choices[0].Message.ReasoningContent != nil {
-    //     fmt.Println(*resp.Choices[0].Message.ReasoningContent)
-    // }
-    // 打印聊天完成请求的响应结果
-    fmt.Println(*resp.Choices[0].Message.Content.StringValue)
-}
\ No newline at end of file
+// findings 按 FormatResultsToString 的编号顺序返回所有命中，返回的指针指向 results 中的 MatchItem
+func findings(results []*matcher.MatchResult) []*matcher.MatchItem {
+	var items []*matcher.MatchItem
+	for _, mr := range results {
+		if mr.Error != nil {
+			continue
+		}
+		for i := range mr.Items {
+			items = append(items, &mr.Items[i])
+		}
+	}
+	return items
+}
+
+// maxAttempts 是大模型的回复不符合 schema 时最多请求的次数
+const maxAttempts = 2
+
+// Analyzer 使用提示词模板把匹配结果交给大模型分析
+type Analyzer struct {
+	p       Provider
+	cfg     config.AI
+	lang    string
+	prompt  *template.Template
+	rules   []config.Rule
+	targets []string
+}
+
+// New 加载 cfg.Language 对应的提示词模板并创建 Analyzer。rules 和 targets（扫描的 URL、本地路径或流量文件）
+// 提供给提示词模板使用，语言没有对应的模板或模板无效时返回错误。
+func New(p Provider, cfg config.AI, rules []config.Rule, targets []string) (*Analyzer, error) {
+	tmpl, err := loadPrompt(cfg, cfg.Language)
+	if err != nil {
+		return nil, err
+	}
+	return &Analyzer{p: p, cfg: cfg, lang: cfg.Language, prompt: tmpl, rules: rules, targets: targets}, nil
+}
+
+// Analyze 将匹配结果按 ai 配置分批交给大模型分析，要求其按 verdictSchema 对每条命中给出 JSON 格式的判断，
+// 校验通过的判断写入对应 MatchItem 的 AI 字段，所有批次的判断合并在同一份结果中。
+// ai.redact 为 true 时只发送脱敏后的 secret 和结构特征；被 block_severities / block_tags 排除的命中不发送，也不会得到判断。
+// 各批次并发请求，遇到限流时按指数退避重试；回复不符合 schema 时附上错误原因重新请求一次，仍然无效时只保留其中有效的判断。
+// 部分批次失败时记录日志并保留其他批次的判断，所有批次都失败时返回第一个错误。
+func (a *Analyzer) Analyze(results []*matcher.MatchResult) error {
+	cfg := a.cfg
+	items := findings(results)
+	total, excluded := len(items), 0
+	for _, item := range items {
+		if blocked(cfg, *item) {
+			excluded++
+		}
+	}
+	if excluded > 0 {
+		log.Printf("[*] %d finding(s) excluded from AI analysis by ai.block_severities/ai.block_tags", excluded)
+	}
+	if total == excluded {
+		return nil
+	}
+
+	// 不含命中的提示词即模板本身的开销，剩余的预算留给命中列表
+	r := newRedactor(results, cfg)
+	base, err := render(a.prompt, a.promptData(&batch{}, r, ""))
+	if err != nil {
+		return err
+	}
+	batches := buildBatches(results, cfg, r, cfg.MaxPromptTokens-estimateTokens(base)-retryNoteTokens)
+	if len(batches) > 1 {
+		log.Printf("[*] Splitting %d finding(s) into %d AI batch(es) by %s", total, len(batches), cfg.BatchBy)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		analyzed  int
+		positives int
+		failed    int
+		firstErr  error
+	)
+	sem := make(chan struct{}, cfg.Concurrency)
+	for i, b := range batches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, b *batch) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			verdicts, err := a.analyzeBatch(context.Background(), b, r)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("[!] AI analysis failed for batch %d/%d (%s, %d finding(s)): %v", i+1, len(batches), b.key, len(b.items), err)
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			// 各批次的命中互不重叠，写入判断不会冲突
+			for id, v := range verdicts {
+				v := v
+				b.items[id-1].AI = &v
+				analyzed++
+				if v.TruePositive {
+					positives++
+				}
+			}
+		}(i, b)
+	}
+	wg.Wait()
+
+	if failed == len(batches) {
+		return firstErr
+	}
+	log.Printf("[*] AI analyzed %d of %d finding(s) in %d batch(es), %d judged true positive", analyzed, total, len(batches), positives)
+	return nil
+}
+
+// analyzeBatch 用提示词模板生成按 r 脱敏的一批命中的提示词并发送给大模型，返回按本批编号索引的有效判断；
+// 只有生成提示词或请求大模型失败时返回错误
+func (a *Analyzer) analyzeBatch(ctx context.Context, b *batch, r *redactor) (map[int]matcher.Verdict, error) {
+	var (
+		best     map[int]matcher.Verdict
+		retryErr string
+	)
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		prompt, err := render(a.prompt, a.promptData(b, r, retryErr))
+		if err != nil {
+			return nil, err
+		}
+		content, err := chatWithRetry(ctx, a.p, prompt, a.cfg.MaxRetries)
+		if err != nil {
+			return nil, err
+		}
+		verdicts, err := parseVerdicts(content, len(b.items))
+		if len(verdicts) > len(best) {
+			best = verdicts
+		}
+		if err == nil {
+			break
+		}
+		log.Printf("[!] Invalid AI verdicts for %s (attempt %d/%d): %v", b.key, attempt, maxAttempts, err)
+		retryErr = err.Error()
+	}
+	return best, nil
+}
+
+// promptData 生成一批命中的模板数据，编号与 formatResults 一致
+func (a *Analyzer) promptData(b *batch, r *redactor, retryErr string) promptData {
+	data := promptData{
+		Language:   a.lang,
+		Schema:     verdictSchema,
+		Redacted:   a.cfg.Redact,
+		Targets:    r.texts(a.targets),
+		Target:     r.text(b.key),
+		Findings:   formatResults(b.results, r),
+		RetryError: retryErr,
+	}
+
+	used := make(map[string]bool)
+	id := 0
+	for _, mr := range b.results {
+		pr := promptResult{URL: r.text(mr.URL)}
+		for _, item := range mr.Items {
+			id++
+			redacted, hints := r.item(item)
+			pr.Findings = append(pr.Findings, promptFinding{ID: id, MatchItem: redacted, Hints: hints})
+			used[item.RuleName] = true
+		}
+		data.Results = append(data.Results, pr)
+	}
+	for _, rule := range a.rules {
+		if used[rule.Name] {
+			data.Rules = append(data.Rules, rule)
+			used[rule.Name] = false
+		}
+	}
+	return data
+}
@@ -0,0 +1,72 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime"
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+	"github.com/volcengine/volcengine-go-sdk/volcengine"
+)
+
+// arkProvider 通过火山方舟 SDK 调用推理接入点
+type arkProvider struct {
+	client     *arkruntime.Client
+	endpointID string
+}
+
+func newArkProvider(baseURL string, endpointID string, apiKey string, timeout time.Duration) *arkProvider {
+	opts := []arkruntime.ConfigOption{
+		//深度推理模型耗费时间会较长，请您设置较大的超时时间，避免超时导致任务失败。推荐30分钟以上
+		arkruntime.WithTimeout(timeout),
+		// 限流重试由 chatWithRetry 按 ai.max_retries 统一处理，关闭 SDK 自带的重试
+		arkruntime.WithRetryTimes(0),
+	}
+	if baseURL != "" {
+		opts = append(opts, arkruntime.WithBaseUrl(baseURL))
+	}
+	return &arkProvider{
+		client:     arkruntime.NewClientWithApiKey(apiKey, opts...),
+		endpointID: endpointID,
+	}
+}
+
+func (p *arkProvider) Chat(ctx context.Context, prompt string) (string, error) {
+	// 构建聊天完成请求，设置请求的模型和消息内容
+	req := model.ChatCompletionRequest{
+		Model: p.endpointID,
+		Messages: []*model.ChatCompletionMessage{
+			{
+				// 消息的角色为用户
+				Role: model.ChatMessageRoleUser,
+				Content: &model.ChatCompletionMessageContent{
+					StringValue: volcengine.String(prompt),
+				},
+			},
+		},
+	}
+
+	resp, err := p.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return "", arkError(err)
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == nil || resp.Choices[0].Message.Content.StringValue == nil {
+		return "", fmt.Errorf("ark: empty response")
+	}
+	return *resp.Choices[0].Message.Content.StringValue, nil
+}
+
+// arkError 将 SDK 返回的带状态码的错误转换为 *StatusError，以便限流时按统一的策略重试
+func arkError(err error) error {
+	var apiErr *model.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode > 0 {
+		return &StatusError{URL: "ark", StatusCode: apiErr.HTTPStatusCode, Body: err.Error()}
+	}
+	var reqErr *model.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode > 0 {
+		return &StatusError{URL: "ark", StatusCode: reqErr.HTTPStatusCode, Body: err.Error()}
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"SecureJS/config"
+	"SecureJS/internal/matcher"
+)
+
+// retryNoteTokens 是为重新请求时追加的错误说明预留的 token 数
+const retryNoteTokens = 500
+
+// minBatchTokens 是每批命中列表的最小 token 预算，max_prompt_tokens 配置得过小时使用
+const minBatchTokens = 1000
+
+// 限流重试的退避时间：第 n 次重试等待 retryBaseDelay * 2^n，加上随机抖动，不超过 retryMaxDelay
+const (
+	retryBaseDelay = 2 * time.Second
+	retryMaxDelay  = 60 * time.Second
+)
+
+// batch 是一次发送给大模型的一批命中
+type batch struct {
+	key     string                 // 本批命中所属的主机或 URL
+	results []*matcher.MatchResult // 发送给大模型的结果，只包含本批的命中
+	items   []*matcher.MatchItem   // 本批命中在原始结果中的位置，顺序与 FormatResultsToString(results) 的编号一致
+	tokens  int                    // 本批命中列表的估算 token 数
+}
+
+// add 将 mr 中的一条命中加入本批，item 指向原始结果中的 MatchItem
+func (b *batch) add(mr *matcher.MatchResult, item *matcher.MatchItem, tokens int) {
+	if n := len(b.results); n == 0 || b.results[n-1].URL != mr.URL {
+		b.results = append(b.results, &matcher.MatchResult{URL: mr.URL})
+	}
+	last := b.results[len(b.results)-1]
+	last.Items = append(last.Items, *item)
+	b.items = append(b.items, item)
+	b.tokens += tokens
+}
+
+// buildBatches 按 cfg.BatchBy（host / url）将命中分组，每组再按 budget 切分为多批，使每批按 r 脱敏后的命中列表的估算 token 数不超过 budget。
+// 单条命中超过 budget 时单独成批。被 block_severities / block_tags 排除的命中和请求失败的 URL 不发送给大模型。
+func buildBatches(results []*matcher.MatchResult, cfg config.AI, r *redactor, budget int) []*batch {
+	if budget < minBatchTokens {
+		budget = minBatchTokens
+	}
+
+	var batches []*batch
+	current := make(map[string]*batch) // 每个分组正在填充的批次
+	for _, mr := range results {
+		if mr.Error != nil || len(mr.Items) == 0 {
+			continue
+		}
+		key := mr.URL
+		if cfg.BatchBy == "host" {
+			if u, err := url.Parse(mr.URL); err == nil && u.Host != "" {
+				key = u.Host
+			}
+		}
+		headerTokens := estimateTokens(formatURL(r.text(mr.URL), len(mr.Items)))
+
+		for i := range mr.Items {
+			item := &mr.Items[i]
+			if blocked(cfg, *item) {
+				continue
+			}
+			// 编号的位数对估算影响很小，统一按 1 计算
+			b := current[key]
+			tokens := estimateTokens(r.format(1, *item))
+			cost := tokens
+			if b == nil || b.results[len(b.results)-1].URL != mr.URL {
+				cost += headerTokens
+			}
+			if b == nil || b.tokens+cost > budget {
+				b = &batch{key: key}
+				current[key] = b
+				batches = append(batches, b)
+				cost = tokens + headerTokens
+				if cost > budget {
+					log.Printf("[!] A finding on %s exceeds the AI prompt budget (%d > %d tokens), sending it alone", mr.URL, cost, budget)
+				}
+			}
+			b.add(mr, item, cost)
+		}
+	}
+	return batches
+}
+
+// estimateTokens 粗略估算文本的 token 数。各模型的分词器不同，这里按偏保守的比例估算：
+// ASCII 字符约 3 个一个 token（密钥、base64 等随机字符串的分词效率较低），其他字符（如中文）约一个字符一个 token
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < 0x80 {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+2)/3 + other
+}
+
+// chatWithRetry 调用 p.Chat，遇到限流或服务暂时不可用时按指数退避重试，最多重试 maxRetries 次
+func chatWithRetry(ctx context.Context, p Provider, prompt string, maxRetries int) (string, error) {
+	for attempt := 0; ; attempt++ {
+		content, err := p.Chat(ctx, prompt)
+		if err == nil {
+			return content, nil
+		}
+		delay, retryable := retryDelay(err, attempt)
+		if !retryable || attempt >= maxRetries {
+			return "", err
+		}
+		log.Printf("[!] AI request throttled or unavailable, retrying in %s (%d/%d): %v", delay.Round(time.Millisecond), attempt+1, maxRetries, err)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryDelay 判断 err 是否可以重试（429 限流、502/503/504 服务暂时不可用），返回第 attempt 次重试前的等待时间。
+// 响应带有 Retry-After 时按其等待。
+func retryDelay(err error, attempt int) (time.Duration, bool) {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return 0, false
+	}
+	switch se.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		return 0, false
+	}
+	if se.RetryAfter > 0 {
+		return se.RetryAfter, true
+	}
+	delay := retryBaseDelay << attempt
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	// 加上最多 50% 的随机抖动，避免并发的批次同时重试
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1)), true
+}
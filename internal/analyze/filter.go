@@ -0,0 +1,31 @@
+package analyze
+
+import "SecureJS/internal/matcher"
+
+// FilterModes 是 --ai-filter 的可选值：
+// all 保留所有命中；true-positive 去掉被 AI 判断为误报的命中（没有得到判断的命中仍然保留）；
+// false-positive 只保留被 AI 判断为误报的命中，便于复查
+var FilterModes = []string{"all", "true-positive", "false-positive"}
+
+// Filter 按 AI 判断过滤命中，返回新的结果列表，不修改 results；请求失败的 URL 始终保留
+func Filter(results []*matcher.MatchResult, mode string) []*matcher.MatchResult {
+	if mode == "" || mode == "all" {
+		return results
+	}
+	filtered := make([]*matcher.MatchResult, 0, len(results))
+	for _, mr := range results {
+		if mr.Error != nil {
+			filtered = append(filtered, mr)
+			continue
+		}
+		var items []matcher.MatchItem
+		for _, item := range mr.Items {
+			falsePositive := item.AI != nil && !item.AI.TruePositive
+			if falsePositive == (mode == "false-positive") {
+				items = append(items, item)
+			}
+		}
+		filtered = append(filtered, &matcher.MatchResult{URL: mr.URL, Items: items})
+	}
+	return filtered
+}
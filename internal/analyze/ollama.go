@@ -0,0 +1,36 @@
+package analyze
+
+import (
+	"context"
+	"net/http"
+)
+
+// ollamaProvider 调用 Ollama 的 /api/chat 接口，适合在无法访问外网的环境中使用本地模型
+type ollamaProvider struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+type ollamaChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+type ollamaChatResponse struct {
+	Message chatMessage `json:"message"`
+}
+
+func (p *ollamaProvider) Chat(ctx context.Context, prompt string) (string, error) {
+	req := ollamaChatRequest{
+		Model:    p.model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+	}
+
+	var resp ollamaChatResponse
+	if err := postJSON(ctx, p.client, p.baseURL+"/api/chat", nil, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
@@ -0,0 +1,48 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// openAIProvider 调用 OpenAI 兼容的 /chat/completions 接口，
+// 同样适用于 vLLM、LM Studio、llama.cpp server 等本地部署的模型
+type openAIProvider struct {
+	baseURL string
+	model   string
+	apiKey  string // 本地部署的模型通常不需要，可以为空
+	client  *http.Client
+}
+
+type openAIChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (p *openAIProvider) Chat(ctx context.Context, prompt string) (string, error) {
+	header := make(http.Header)
+	if p.apiKey != "" {
+		header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	req := openAIChatRequest{
+		Model:    p.model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+	}
+
+	var resp openAIChatResponse
+	if err := postJSON(ctx, p.client, p.baseURL+"/chat/completions", header, req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("openai: empty response")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,95 @@
+package analyze
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"SecureJS/config"
+	"SecureJS/internal/matcher"
+)
+
+// 内置的提示词模板，文件名为语言代码
+//
+//go:embed prompts/*.tmpl
+var builtinPrompts embed.FS
+
+// Languages 是内置提示词模板支持的语言
+var Languages = []string{"en", "zh"}
+
+// promptData 是提示词模板可以使用的数据，模板按 text/template 的语法引用其中的字段
+type promptData struct {
+	Language   string         // 提示词的语言，如 en、zh
+	Schema     string         // 要求大模型输出的 JSON Schema
+	Redacted   bool           // secret 是否已经脱敏（ai.redact）
+	Targets    []string       // 本次扫描的目标：URL、本地路径或流量文件
+	Target     string         // 本批命中所属的主机或 URL（由 ai.batch_by 决定）
+	Rules      []config.Rule  // 本批命中涉及的规则，按配置文件中的顺序
+	Results    []promptResult // 本批命中按 URL 分组，secret 已按需脱敏
+	Findings   string         // 按 FormatResultsToString 格式化（并按需脱敏）的本批命中列表
+	RetryError string         // 上一次回复不符合 schema 的原因，首次请求时为空
+}
+
+type promptResult struct {
+	URL      string
+	Findings []promptFinding
+}
+
+// promptFinding 是一条命中，ID 为大模型判断时引用的编号，Hints 为脱敏时 secret 的结构特征
+type promptFinding struct {
+	ID int
+	matcher.MatchItem
+	Hints string
+}
+
+// loadPrompt 加载 lang 对应的提示词模板：ai.prompt_templates 中配置了该语言时读取对应的文件，否则使用内置模板
+func loadPrompt(cfg config.AI, lang string) (*template.Template, error) {
+	var text string
+	if file, ok := cfg.PromptTemplates[lang]; ok && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template %s: %w", file, err)
+		}
+		text = string(data)
+	} else {
+		data, err := builtinPrompts.ReadFile("prompts/" + lang + ".tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("no prompt template for language '%s', valid values: %s", lang, strings.Join(promptLanguages(cfg), ", "))
+		}
+		text = string(data)
+	}
+
+	tmpl, err := template.New(lang).Funcs(template.FuncMap{
+		"join":  strings.Join,
+		"upper": strings.ToUpper,
+	}).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template for language '%s': %w", lang, err)
+	}
+	return tmpl, nil
+}
+
+// promptLanguages 返回可以选择的语言：内置的语言加上 ai.prompt_templates 中配置的语言
+func promptLanguages(cfg config.AI) []string {
+	langs := append([]string{}, Languages...)
+	for lang := range cfg.PromptTemplates {
+		if !slices.Contains(langs, lang) {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// render 用模板生成提示词
+func render(tmpl *template.Template, data promptData) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return sb.String(), nil
+}
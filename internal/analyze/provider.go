@@ -0,0 +1,168 @@
+package analyze
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"SecureJS/config"
+)
+
+// Provider 是 AI 分析使用的大模型后端
+type Provider interface {
+	// Chat 发送一条用户消息，返回模型的回复
+	Chat(ctx context.Context, prompt string) (string, error)
+}
+
+// 各后端的默认接口地址和保存 API Key 的默认环境变量
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOllamaBaseURL = "http://localhost:11434"
+	defaultArkKeyEnv     = "ARK_API_KEY"
+	defaultOpenAIKeyEnv  = "OPENAI_API_KEY"
+)
+
+// NewProvider 根据 ai 配置创建对应的后端。apiKey 为空时从 cfg.APIKeyEnv 指定的环境变量读取
+// （未配置时 ark 为 ARK_API_KEY，openai 为 OPENAI_API_KEY，ollama 不需要 API Key）。
+func NewProvider(cfg config.AI, apiKey string) (Provider, error) {
+	keyEnv := cfg.APIKeyEnv
+	if keyEnv == "" {
+		switch cfg.Provider {
+		case "ark":
+			keyEnv = defaultArkKeyEnv
+		case "openai":
+			keyEnv = defaultOpenAIKeyEnv
+		}
+	}
+	if apiKey == "" && keyEnv != "" {
+		apiKey = os.Getenv(keyEnv)
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+
+	switch cfg.Provider {
+	case "ark":
+		if apiKey == "" {
+			return nil, fmt.Errorf("ark provider requires an API key, set %s or use -k", keyEnv)
+		}
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("ark provider requires ai.model (endpoint ID) or -i")
+		}
+		return newArkProvider(cfg.BaseURL, cfg.Model, apiKey, timeout), nil
+	case "openai":
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("openai provider requires ai.model or -i")
+		}
+		return &openAIProvider{
+			baseURL: baseURLOr(cfg.BaseURL, defaultOpenAIBaseURL),
+			model:   cfg.Model,
+			apiKey:  apiKey,
+			client:  &http.Client{Timeout: timeout},
+		}, nil
+	case "ollama":
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("ollama provider requires ai.model or -i")
+		}
+		return &ollamaProvider{
+			baseURL: baseURLOr(cfg.BaseURL, defaultOllamaBaseURL),
+			model:   cfg.Model,
+			client:  &http.Client{Timeout: timeout},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider '%s'", cfg.Provider)
+	}
+}
+
+// baseURLOr 返回去掉末尾 / 的 baseURL，为空时返回 def
+func baseURLOr(baseURL string, def string) string {
+	if baseURL == "" {
+		baseURL = def
+	}
+	return strings.TrimSuffix(baseURL, "/")
+}
+
+// StatusError 表示大模型接口返回了非 2xx 状态码，RetryAfter 为响应中 Retry-After 指定的等待时间
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s returned status %d", e.URL, e.StatusCode)
+	}
+	return fmt.Sprintf("%s returned status %d: %s", e.URL, e.StatusCode, e.Body)
+}
+
+// parseRetryAfter 解析以秒数或 HTTP 日期表示的 Retry-After，无法解析时返回 0
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// chatMessage 是 OpenAI 和 Ollama 接口共用的消息格式
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// postJSON 以 JSON 格式发送 body 并把响应解析到 out，非 2xx 响应返回带响应内容的 *StatusError
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response from %s: %w", url, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet := string(respBody)
+		if len(snippet) > 500 {
+			snippet = snippet[:500]
+		}
+		return &StatusError{
+			URL:        url,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(snippet),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", url, err)
+	}
+	return nil
+}
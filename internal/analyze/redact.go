@@ -0,0 +1,244 @@
+package analyze
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"SecureJS/config"
+	"SecureJS/internal/matcher"
+)
+
+// knownPrefixes 是常见凭据的固定前缀，脱敏时保留，便于大模型判断类型
+var knownPrefixes = []string{
+	"AKIA", "ASIA", "ABIA", "ACCA", "LTAI", "AIza", "ya29.",
+	"ghp_", "gho_", "ghu_", "ghs_", "ghr_", "github_pat_", "glpat-",
+	"xoxb-", "xoxp-", "xoxa-", "xoxr-", "xoxs-",
+	"sk_live_", "sk_test_", "pk_live_", "pk_test_", "rk_live_", "sk-ant-", "sk-proj-", "sk-",
+	"hf_", "npm_", "SG.", "eyJ", "-----BEGIN",
+}
+
+// minRedactLength 是在文本中直接替换的 secret 的最小长度，更短的取值只按完整单词替换，避免误伤上下文中的其他内容
+const minRedactLength = 4
+
+// keyNamePattern 匹配 secret 前面的字段名，如 accessKey: "、"aws_secret" = '、token=
+var keyNamePattern = regexp.MustCompile(`([A-Za-z_$][\w$.-]*)["']?\s*(?::|=>|:=|=)\s*["'\x60]?\s*$`)
+
+// redactor 在发送给大模型的文本中把 secret 替换为脱敏形式。
+// ai.redact 为 true 时替换所有命中的 secret 并附上结构特征；否则只替换被 block_severities / block_tags 排除的命中，
+// 避免它们出现在其他命中的上下文中。
+type redactor struct {
+	hints    bool              // 是否为每条命中附上 secret 的结构特征
+	secrets  map[string]bool   // 需要脱敏的 secret
+	replacer *strings.Replacer // 把长度不小于 minRedactLength 的原始取值替换为脱敏形式
+	short    *regexp.Regexp    // 匹配作为完整单词出现的较短 secret，没有时为 nil
+}
+
+// newRedactor 根据 cfg 创建 redactor，不需要替换任何内容时返回 nil
+func newRedactor(results []*matcher.MatchResult, cfg config.AI) *redactor {
+	seen := make(map[string]bool)
+	var secrets, short []string
+	for _, item := range findings(results) {
+		if !cfg.Redact && !blocked(cfg, *item) {
+			continue
+		}
+		if item.Secret == "" || seen[item.Secret] {
+			continue
+		}
+		seen[item.Secret] = true
+		if len([]rune(item.Secret)) >= minRedactLength {
+			secrets = append(secrets, item.Secret)
+		} else {
+			short = append(short, item.Secret)
+		}
+	}
+	if !cfg.Redact && len(seen) == 0 {
+		return nil
+	}
+
+	// strings.Replacer 按参数顺序匹配，较长的 secret 先替换，避免只替换了其中的一部分
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	pairs := make([]string, 0, len(secrets)*2)
+	for _, s := range secrets {
+		pairs = append(pairs, s, maskSecret(s))
+	}
+	return &redactor{hints: cfg.Redact, secrets: seen, replacer: strings.NewReplacer(pairs...), short: wordPattern(short)}
+}
+
+// wordPattern 返回匹配 secrets 中任意一个完整单词的正则，secret 首尾是单词字符时要求前后不与其他单词字符相连
+func wordPattern(secrets []string) *regexp.Regexp {
+	if len(secrets) == 0 {
+		return nil
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	alts := make([]string, len(secrets))
+	for i, s := range secrets {
+		alt := regexp.QuoteMeta(s)
+		if isWordByte(s[0]) {
+			alt = `\b` + alt
+		}
+		if isWordByte(s[len(s)-1]) {
+			alt += `\b`
+		}
+		alts[i] = alt
+	}
+	return regexp.MustCompile(strings.Join(alts, "|"))
+}
+
+// isWordByte 判断 b 是否为正则 \b 所用的 ASCII 单词字符
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// text 替换文本中的 secret，r 为 nil 时原样返回
+func (r *redactor) text(s string) string {
+	if r == nil {
+		return s
+	}
+	s = r.replacer.Replace(s)
+	if r.short != nil {
+		s = r.short.ReplaceAllStringFunc(s, maskSecret)
+	}
+	return s
+}
+
+// texts 替换每段文本中的 secret，r 为 nil 时原样返回
+func (r *redactor) texts(list []string) []string {
+	if r == nil {
+		return list
+	}
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = r.text(s)
+	}
+	return out
+}
+
+// item 返回脱敏后的命中和 secret 的结构特征（未开启 ai.redact 时为空），r 为 nil 时原样返回。
+// 命中自身的 secret 需要脱敏时无论长短都会被遮盖，并替换该命中文本中的所有出现位置
+func (r *redactor) item(item matcher.MatchItem) (matcher.MatchItem, string) {
+	if r == nil {
+		return item, ""
+	}
+	redacted := item
+	redacted.MatchedText = r.text(item.MatchedText)
+	redacted.Context = r.text(item.Context)
+	redacted.Location = r.text(item.Location)
+	if !r.hints && !r.secrets[item.Secret] {
+		redacted.Secret = r.text(item.Secret)
+		return redacted, ""
+	}
+	if item.Secret != "" {
+		mask := maskSecret(item.Secret)
+		redacted.MatchedText = strings.ReplaceAll(redacted.MatchedText, item.Secret, mask)
+		redacted.Context = strings.ReplaceAll(redacted.Context, item.Secret, mask)
+		redacted.Location = strings.ReplaceAll(redacted.Location, item.Secret, mask)
+		redacted.Secret = mask
+	}
+	if !r.hints {
+		return redacted, ""
+	}
+	return redacted, secretHints(item)
+}
+
+// format 返回脱敏后的命中文本，r 为 nil 时与 formatFinding 相同
+func (r *redactor) format(id int, item matcher.MatchItem) string {
+	redacted, hints := r.item(item)
+	text := formatFinding(id, redacted)
+	if hints != "" {
+		text += "      Secret hints: " + hints + "\n"
+	}
+	return text
+}
+
+// maskSecret 返回 secret 的脱敏形式：保留常见凭据的固定前缀，其余字符替换为 *
+func maskSecret(secret string) string {
+	prefix := secretPrefix(secret)
+	return prefix + strings.Repeat("*", len([]rune(secret))-len([]rune(prefix)))
+}
+
+// secretPrefix 返回 secret 开头的已知固定前缀，没有时返回空字符串
+func secretPrefix(secret string) string {
+	var prefix string
+	for _, p := range knownPrefixes {
+		if strings.HasPrefix(secret, p) && len(p) > len(prefix) && len(p) < len(secret) {
+			prefix = p
+		}
+	}
+	return prefix
+}
+
+// secretHints 返回 secret 的结构特征，如 key=aws_secret_access_key, length=40, charset=base64, entropy=4.71, prefix=AKIA
+func secretHints(item matcher.MatchItem) string {
+	hints := []string{}
+	if key := keyName(item); key != "" {
+		hints = append(hints, "key="+key)
+	}
+	hints = append(hints,
+		fmt.Sprintf("length=%d", len([]rune(item.Secret))),
+		"charset="+charsetOf(item.Secret),
+		fmt.Sprintf("entropy=%.2f", item.Entropy),
+	)
+	if prefix := secretPrefix(item.Secret); prefix != "" {
+		hints = append(hints, "prefix="+prefix)
+	}
+	return strings.Join(hints, ", ")
+}
+
+// keyName 返回 secret 所赋值的字段名：响应头中的命中为响应头名称，
+// 否则取整段匹配（整段匹配就是 secret 时取上下文）中 secret 前面的字段名
+func keyName(item matcher.MatchItem) string {
+	if name, ok := strings.CutPrefix(item.Location, "header:"); ok {
+		return name
+	}
+	if item.Secret == "" {
+		return ""
+	}
+	for _, text := range []string{item.MatchedText, item.Context} {
+		if idx := strings.Index(text, item.Secret); idx > 0 {
+			if m := keyNamePattern.FindStringSubmatch(text[:idx]); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+// 各字符集允许的字符，按从窄到宽排列，base64 同时兼容 base64url
+var charsets = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"digits", regexp.MustCompile(`^[0-9]+$`)},
+	{"hex", regexp.MustCompile(`^[0-9A-Fa-f]+$`)},
+	{"alnum", regexp.MustCompile(`^[A-Za-z0-9]+$`)},
+	{"base64", regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)},
+}
+
+// charsetOf 返回 secret 使用的最窄的字符集：digits、hex、alnum、base64，都不符合时为 mixed
+func charsetOf(s string) string {
+	for _, cs := range charsets {
+		if cs.pattern.MatchString(s) {
+			return cs.name
+		}
+	}
+	return "mixed"
+}
+
+// blocked 判断命中是否被 ai.block_severities / ai.block_tags 排除，不发送给大模型
+func blocked(cfg config.AI, item matcher.MatchItem) bool {
+	for _, s := range cfg.BlockSeverities {
+		if item.Severity == s {
+			return true
+		}
+	}
+	for _, t := range cfg.BlockTags {
+		for _, tag := range item.Tags {
+			if strings.EqualFold(tag, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package analyze
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"SecureJS/internal/matcher"
+)
+
+// verdictSchema 是要求大模型输出的 JSON Schema，parseVerdicts 按同样的约束校验回复
+const verdictSchema = `{
+  "type": "object",
+  "required": ["verdicts"],
+  "additionalProperties": false,
+  "properties": {
+    "verdicts": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["id", "true_positive", "confidence", "secret_type", "reasoning", "environment"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer", "minimum": 1, "description": "the finding's number"},
+          "true_positive": {"type": "boolean", "description": "whether the finding is a real leaked secret"},
+          "confidence": {"type": "number", "minimum": 0, "maximum": 1, "description": "confidence in this verdict"},
+          "secret_type": {"type": "string", "description": "type of the secret, e.g. AWS access key"},
+          "reasoning": {"type": "string", "minLength": 1, "description": "why the finding is or is not a real leak"},
+          "environment": {"type": "string", "enum": ["production", "staging", "development", "test", "unknown"], "description": "the environment the secret most likely belongs to"}
+        }
+      }
+    }
+  }
+}`
+
+// environments 是 verdict 中 environment 的可选值
+var environments = []string{"production", "staging", "development", "test", "unknown"}
+
+// verdictReply 对应 verdictSchema，字段使用指针以区分缺失和零值
+type verdictReply struct {
+	Verdicts *[]rawVerdict `json:"verdicts"`
+}
+
+type rawVerdict struct {
+	ID           *int     `json:"id"`
+	TruePositive *bool    `json:"true_positive"`
+	Confidence   *float64 `json:"confidence"`
+	SecretType   *string  `json:"secret_type"`
+	Reasoning    *string  `json:"reasoning"`
+	Environment  *string  `json:"environment"`
+}
+
+// parseVerdicts 解析并校验大模型的回复，n 为命中的数量。返回按编号索引的有效判断；
+// 回复不是合法 JSON、有判断不符合 schema 或有命中没有得到判断时同时返回错误，说明所有问题
+func parseVerdicts(reply string, n int) (map[int]matcher.Verdict, error) {
+	var parsed verdictReply
+	dec := json.NewDecoder(bytes.NewReader([]byte(extractJSON(reply))))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("reply is not valid JSON for the schema: %w", err)
+	}
+	if parsed.Verdicts == nil {
+		return nil, fmt.Errorf("reply is missing required field \"verdicts\"")
+	}
+
+	verdicts := make(map[int]matcher.Verdict, n)
+	var problems []string
+	for i, rv := range *parsed.Verdicts {
+		v, id, err := rv.validate(n)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("verdicts[%d]: %v", i, err))
+			continue
+		}
+		if _, dup := verdicts[id]; dup {
+			problems = append(problems, fmt.Sprintf("verdicts[%d]: duplicate id %d", i, id))
+			continue
+		}
+		verdicts[id] = v
+	}
+
+	var missing []string
+	for id := 1; id <= n; id++ {
+		if _, ok := verdicts[id]; !ok {
+			missing = append(missing, strconv.Itoa(id))
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, "missing verdicts for id "+strings.Join(missing, ","))
+	}
+	if len(problems) > 0 {
+		return verdicts, errors.New(strings.Join(problems, "; "))
+	}
+	return verdicts, nil
+}
+
+// validate 检查单条判断是否符合 verdictSchema，n 为命中的数量
+func (rv rawVerdict) validate(n int) (matcher.Verdict, int, error) {
+	var missing []string
+	if rv.ID == nil {
+		missing = append(missing, "id")
+	}
+	if rv.TruePositive == nil {
+		missing = append(missing, "true_positive")
+	}
+	if rv.Confidence == nil {
+		missing = append(missing, "confidence")
+	}
+	if rv.SecretType == nil {
+		missing = append(missing, "secret_type")
+	}
+	if rv.Reasoning == nil {
+		missing = append(missing, "reasoning")
+	}
+	if rv.Environment == nil {
+		missing = append(missing, "environment")
+	}
+	if len(missing) > 0 {
+		return matcher.Verdict{}, 0, fmt.Errorf("missing required field(s) %s", strings.Join(missing, ","))
+	}
+
+	if *rv.ID < 1 || *rv.ID > n {
+		return matcher.Verdict{}, 0, fmt.Errorf("id %d out of range 1-%d", *rv.ID, n)
+	}
+	if *rv.Confidence < 0 || *rv.Confidence > 1 {
+		return matcher.Verdict{}, 0, fmt.Errorf("confidence %v out of range 0-1", *rv.Confidence)
+	}
+	if strings.TrimSpace(*rv.Reasoning) == "" {
+		return matcher.Verdict{}, 0, fmt.Errorf("reasoning is empty")
+	}
+	env := strings.ToLower(strings.TrimSpace(*rv.Environment))
+	if !slices.Contains(environments, env) {
+		return matcher.Verdict{}, 0, fmt.Errorf("environment '%s' is not one of %s", *rv.Environment, strings.Join(environments, ","))
+	}
+
+	return matcher.Verdict{
+		TruePositive: *rv.TruePositive,
+		Confidence:   *rv.Confidence,
+		SecretType:   strings.TrimSpace(*rv.SecretType),
+		Reasoning:    strings.TrimSpace(*rv.Reasoning),
+		Environment:  env,
+	}, *rv.ID, nil
+}
+
+// extractJSON 去掉回复中可能出现的 Markdown 代码块和前后的说明文字，返回第一个 { 到最后一个 } 之间的内容
+func extractJSON(reply string) string {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return reply
+	}
+	return reply[start : end+1]
+}
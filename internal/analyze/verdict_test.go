@@ -0,0 +1,124 @@
+package analyze
+
+import (
+	"strings"
+	"testing"
+
+	"SecureJS/internal/matcher"
+)
+
+func TestParseVerdicts(t *testing.T) {
+	const one = `{"id":1,"true_positive":true,"confidence":0.9,"secret_type":" AWS access key ","reasoning":" live key ","environment":"Production"}`
+	const two = `{"id":2,"true_positive":false,"confidence":0.2,"secret_type":"placeholder","reasoning":"example value","environment":"unknown"}`
+	tests := []struct {
+		name    string
+		reply   string
+		n       int
+		want    map[int]matcher.Verdict
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			reply: `{"verdicts":[` + one + `,` + two + `]}`,
+			n:     2,
+			want: map[int]matcher.Verdict{
+				1: {TruePositive: true, Confidence: 0.9, SecretType: "AWS access key", Reasoning: "live key", Environment: "production"},
+				2: {TruePositive: false, Confidence: 0.2, SecretType: "placeholder", Reasoning: "example value", Environment: "unknown"},
+			},
+		},
+		{
+			name:  "markdown fence",
+			reply: "Here you go:\n```json\n{\"verdicts\":[" + one + "]}\n```",
+			n:     1,
+			want: map[int]matcher.Verdict{
+				1: {TruePositive: true, Confidence: 0.9, SecretType: "AWS access key", Reasoning: "live key", Environment: "production"},
+			},
+		},
+		{name: "not json", reply: "I think these are fine.", n: 1, wantErr: "not valid JSON"},
+		{name: "missing verdicts", reply: `{}`, n: 1, wantErr: `missing required field "verdicts"`},
+		{name: "unknown field", reply: `{"verdicts":[],"summary":"x"}`, n: 0, wantErr: "not valid JSON"},
+		{
+			name:    "missing id",
+			reply:   `{"verdicts":[` + one + `]}`,
+			n:       2,
+			want:    map[int]matcher.Verdict{1: {TruePositive: true, Confidence: 0.9, SecretType: "AWS access key", Reasoning: "live key", Environment: "production"}},
+			wantErr: "missing verdicts for id 2",
+		},
+		{
+			name:    "missing fields",
+			reply:   `{"verdicts":[{"id":1,"true_positive":true}]}`,
+			n:       1,
+			want:    map[int]matcher.Verdict{},
+			wantErr: "missing required field(s) confidence,secret_type,reasoning,environment",
+		},
+		{
+			name:    "out of range",
+			reply:   `{"verdicts":[{"id":3,"true_positive":true,"confidence":1.5,"secret_type":"x","reasoning":"y","environment":"test"}]}`,
+			n:       1,
+			want:    map[int]matcher.Verdict{},
+			wantErr: "id 3 out of range 1-1",
+		},
+		{
+			name:    "confidence",
+			reply:   `{"verdicts":[{"id":1,"true_positive":true,"confidence":1.5,"secret_type":"x","reasoning":"y","environment":"test"}]}`,
+			n:       1,
+			want:    map[int]matcher.Verdict{},
+			wantErr: "confidence 1.5 out of range 0-1",
+		},
+		{
+			name:    "empty reasoning",
+			reply:   `{"verdicts":[{"id":1,"true_positive":true,"confidence":0.5,"secret_type":"x","reasoning":"  ","environment":"test"}]}`,
+			n:       1,
+			want:    map[int]matcher.Verdict{},
+			wantErr: "reasoning is empty",
+		},
+		{
+			name:    "environment",
+			reply:   `{"verdicts":[{"id":1,"true_positive":true,"confidence":0.5,"secret_type":"x","reasoning":"y","environment":"prod"}]}`,
+			n:       1,
+			want:    map[int]matcher.Verdict{},
+			wantErr: "environment 'prod' is not one of",
+		},
+		{
+			name:    "duplicate id",
+			reply:   `{"verdicts":[` + one + `,` + one + `]}`,
+			n:       1,
+			want:    map[int]matcher.Verdict{1: {TruePositive: true, Confidence: 0.9, SecretType: "AWS access key", Reasoning: "live key", Environment: "production"}},
+			wantErr: "verdicts[1]: duplicate id 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVerdicts(tt.reply, tt.n)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("parseVerdicts() error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("parseVerdicts() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseVerdicts() returned %d verdicts, want %d", len(got), len(tt.want))
+			}
+			for id, w := range tt.want {
+				if got[id] != w {
+					t.Errorf("verdict %d = %+v, want %+v", id, got[id], w)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`{"a":1}`, `{"a":1}`},
+		{"```json\n{\"a\":{\"b\":2}}\n```", `{"a":{"b":2}}`},
+		{"no json here", "no json here"},
+		{"} before {", "} before {"},
+	}
+	for _, tt := range tests {
+		if got := extractJSON(tt.in); got != tt.want {
+			t.Errorf("extractJSON(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonCookie 兼容浏览器插件（EditThisCookie 等）导出的 cookie 数组和 Playwright 的 storageState
+type jsonCookie struct {
+	Name           string  `json:"name"`
+	Value          string  `json:"value"`
+	Domain         string  `json:"domain"`
+	Path           string  `json:"path"`
+	Secure         bool    `json:"secure"`
+	HTTPOnly       bool    `json:"httpOnly"`
+	HostOnly       bool    `json:"hostOnly"`
+	ExpirationDate float64 `json:"expirationDate"`
+	Expires        float64 `json:"expires"`
+}
+
+// LoadCookieFile 读取 Netscape cookies.txt 或 JSON 格式的 cookie 文件
+func LoadCookieFile(path string) ([]*http.Cookie, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookie file %s: %w", path, err)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return parseJSONCookies(trimmed)
+	}
+	return parseNetscapeCookies(data)
+}
+
+// parseJSONCookies 解析 [{...}] 或 {"cookies":[{...}]} 形式的 cookie
+func parseJSONCookies(data []byte) ([]*http.Cookie, error) {
+	var list []jsonCookie
+	if data[0] == '{' {
+		var state struct {
+			Cookies []jsonCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("invalid JSON cookie file: %w", err)
+		}
+		list = state.Cookies
+	} else if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid JSON cookie file: %w", err)
+	}
+
+	cookies := make([]*http.Cookie, 0, len(list))
+	for _, jc := range list {
+		if jc.Name == "" || jc.Domain == "" {
+			continue
+		}
+		// 以 . 开头的是域名 cookie，hostOnly 的 cookie 只发给该主机
+		domain := jc.Domain
+		if jc.HostOnly {
+			domain = strings.TrimPrefix(domain, ".")
+		}
+		c := &http.Cookie{
+			Name:     jc.Name,
+			Value:    jc.Value,
+			Domain:   domain,
+			Path:     jc.Path,
+			Secure:   jc.Secure,
+			HttpOnly: jc.HTTPOnly,
+		}
+		if exp := jc.ExpirationDate + jc.Expires; exp > 0 {
+			c.Expires = time.Unix(int64(exp), 0)
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, nil
+}
+
+// parseNetscapeCookies 解析 Netscape cookies.txt：
+// domain \t includeSubdomains \t path \t secure \t expiry \t name \t value，#HttpOnly_ 前缀表示 HttpOnly
+func parseNetscapeCookies(data []byte) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			httpOnly = true
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			continue
+		}
+
+		domain := fields[0]
+		if strings.EqualFold(fields[1], "TRUE") && !strings.HasPrefix(domain, ".") {
+			domain = "." + domain
+		}
+		c := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Domain:   domain,
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if exp, err := strconv.ParseInt(fields[4], 10, 64); err == nil && exp > 0 {
+			c.Expires = time.Unix(exp, 0)
+		}
+		cookies = append(cookies, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Netscape cookie file: %w", err)
+	}
+	return cookies, nil
+}
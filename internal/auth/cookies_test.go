@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadCookieFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []http.Cookie
+		wantErr bool
+	}{
+		{
+			name: "netscape",
+			content: "# Netscape HTTP Cookie File\n" +
+				"\n" +
+				"example.com\tTRUE\t/\tTRUE\t1893456000\tsid\tabc123\n" +
+				"#HttpOnly_www.example.com\tFALSE\t/app\tFALSE\t0\ttoken\txyz\r\n" +
+				"broken\tline\n",
+			want: []http.Cookie{
+				{Name: "sid", Value: "abc123", Domain: ".example.com", Path: "/", Secure: true, Expires: time.Unix(1893456000, 0)},
+				{Name: "token", Value: "xyz", Domain: "www.example.com", Path: "/app", HttpOnly: true},
+			},
+		},
+		{
+			name: "json array",
+			content: `[
+				{"name":"sid","value":"abc","domain":".example.com","path":"/","secure":true,"httpOnly":true,"expirationDate":1893456000.5},
+				{"name":"host","value":"1","domain":".www.example.com","path":"/","hostOnly":true},
+				{"name":"","value":"skip","domain":"example.com"},
+				{"name":"nodomain","value":"skip"}
+			]`,
+			want: []http.Cookie{
+				{Name: "sid", Value: "abc", Domain: ".example.com", Path: "/", Secure: true, HttpOnly: true, Expires: time.Unix(1893456000, 0)},
+				{Name: "host", Value: "1", Domain: "www.example.com", Path: "/"},
+			},
+		},
+		{
+			name:    "storage state",
+			content: `{"cookies":[{"name":"sid","value":"abc","domain":"example.com","path":"/","expires":-1}],"origins":[]}`,
+			want: []http.Cookie{
+				{Name: "sid", Value: "abc", Domain: "example.com", Path: "/"},
+			},
+		},
+		{
+			name:    "invalid json",
+			content: `[{"name":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cookies")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadCookieFile(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("LoadCookieFile() should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadCookieFile() error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("LoadCookieFile() returned %d cookies, want %d", len(got), len(tt.want))
+			}
+			for i, c := range got {
+				w := tt.want[i]
+				if c.Name != w.Name || c.Value != w.Value || c.Domain != w.Domain || c.Path != w.Path ||
+					c.Secure != w.Secure || c.HttpOnly != w.HttpOnly || !c.Expires.Equal(w.Expires) {
+					t.Errorf("cookie %d = %+v, want %+v", i, *c, w)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadCookieFileMissing(t *testing.T) {
+	if _, err := LoadCookieFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadCookieFile() should fail for a missing file")
+	}
+}
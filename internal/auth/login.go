@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"SecureJS/config"
+	"SecureJS/internal/headless"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// defaultLoginTimeout 是登录流程的默认超时时间
+const defaultLoginTimeout = 60 * time.Second
+
+// runLogin 在无头浏览器中按顺序执行登录流程，返回登录后浏览器中的所有 cookie
+func runLogin(flow config.LoginFlow, browserPath string, proxy string, customHeaders []string) ([]*http.Cookie, error) {
+	timeout := defaultLoginTimeout
+	if flow.Timeout > 0 {
+		timeout = time.Duration(flow.Timeout) * time.Second
+	}
+
+	browser := headless.Launch(browserPath, proxy)
+	defer func() {
+		if err := browser.Close(); err != nil {
+			log.Printf("[!] failed to close login browser: %v\n", err)
+		}
+	}()
+
+	page, err := browser.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open login page: %w", err)
+	}
+	defer page.Close()
+
+	if headerPairs := headerPairs(customHeaders); len(headerPairs) > 0 {
+		if _, err := page.SetExtraHeaders(headerPairs); err != nil {
+			return nil, fmt.Errorf("failed to set headers: %w", err)
+		}
+	}
+
+	page = page.Timeout(timeout)
+	for i, step := range flow.Steps {
+		if err := runStep(page, step); err != nil {
+			return nil, fmt.Errorf("login step %d (%s) failed: %w", i+1, step.Action, err)
+		}
+	}
+
+	networkCookies, err := browser.GetCookies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookies after login: %w", err)
+	}
+
+	cookies := make([]*http.Cookie, 0, len(networkCookies))
+	for _, nc := range networkCookies {
+		c := &http.Cookie{
+			Name:     nc.Name,
+			Value:    nc.Value,
+			Domain:   nc.Domain,
+			Path:     nc.Path,
+			Secure:   nc.Secure,
+			HttpOnly: nc.HTTPOnly,
+		}
+		if nc.Expires > 0 {
+			c.Expires = nc.Expires.Time()
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, nil
+}
+
+// runStep 执行登录流程中的一步
+func runStep(page *rod.Page, step config.LoginStep) error {
+	switch strings.ToLower(step.Action) {
+	case "navigate":
+		if err := page.Navigate(os.ExpandEnv(step.URL)); err != nil {
+			return err
+		}
+		return page.WaitLoad()
+
+	case "fill":
+		el, err := page.Element(step.Selector)
+		if err != nil {
+			return err
+		}
+		if err := el.SelectAllText(); err != nil {
+			return err
+		}
+		return el.Input(os.ExpandEnv(step.Value))
+
+	case "click":
+		el, err := page.Element(step.Selector)
+		if err != nil {
+			return err
+		}
+		return el.Click(proto.InputMouseButtonLeft, 1)
+
+	case "submit":
+		// selector 可以是 form 本身，也可以是 form 中的任意一个字段
+		el, err := page.Element(step.Selector)
+		if err != nil {
+			return err
+		}
+		_, err = el.Eval(`() => (this.form || this).requestSubmit ? (this.form || this).requestSubmit() : (this.form || this).submit()`)
+		return err
+
+	case "wait":
+		_, err := page.Element(step.Selector)
+		return err
+
+	case "sleep":
+		time.Sleep(time.Duration(step.Seconds) * time.Second)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown action '%s'", step.Action)
+	}
+}
+
+// headerPairs 将 -H 参数的 "Key: Value" 转换为 page.SetExtraHeaders 需要的键值对
+func headerPairs(customHeaders []string) []string {
+	var pairs []string
+	for _, h := range customHeaders {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) == 2 {
+			pairs = append(pairs, strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+		}
+	}
+	return pairs
+}
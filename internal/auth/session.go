@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"SecureJS/config"
+
+	"github.com/go-rod/rod/lib/proto"
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxRefreshes 限制一次运行中重新登录的次数，避免失效判断误报时反复登录
+const maxRefreshes = 5
+
+// Session 是无头浏览器（crawler）和二次请求（parser）共用的登录会话。
+// nil 的 *Session 表示不做认证，所有方法都可以安全调用。
+type Session struct {
+	mu         sync.Mutex
+	jar        *cookiejar.Jar
+	cookies    []*http.Cookie // 所有已知 cookie（带 Domain/Path），用于同步到浏览器
+	generation int            // 每次重新登录后递增，用于合并并发的刷新请求
+	refreshes  int
+
+	login         config.LoginFlow
+	expiredStatus map[int]bool
+	expiredURL    *regexp.Regexp
+	expiredBody   *regexp.Regexp
+
+	browserPath   string
+	proxy         string
+	customHeaders []string
+}
+
+// New 根据配置创建会话：先导入 cookie 文件，再执行登录流程（如果配置了的话）。
+// 既没有 cookie 文件也没有登录流程时返回 nil。
+func New(cfg config.Auth, browserPath string, proxy string, customHeaders []string) (*Session, error) {
+	if cfg.CookieFile == "" && len(cfg.Login.Steps) == 0 {
+		return nil, nil
+	}
+
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		jar:           jar,
+		login:         cfg.Login,
+		expiredStatus: make(map[int]bool),
+		browserPath:   browserPath,
+		proxy:         proxy,
+		customHeaders: customHeaders,
+	}
+	for _, code := range cfg.Expired.Status {
+		s.expiredStatus[code] = true
+	}
+	if cfg.Expired.URL != "" {
+		if s.expiredURL, err = regexp.Compile(cfg.Expired.URL); err != nil {
+			return nil, fmt.Errorf("invalid auth expired url regex '%s': %w", cfg.Expired.URL, err)
+		}
+	}
+	if cfg.Expired.Body != "" {
+		if s.expiredBody, err = regexp.Compile(cfg.Expired.Body); err != nil {
+			return nil, fmt.Errorf("invalid auth expired body regex '%s': %w", cfg.Expired.Body, err)
+		}
+	}
+
+	if cfg.CookieFile != "" {
+		cookies, err := LoadCookieFile(cfg.CookieFile)
+		if err != nil {
+			return nil, err
+		}
+		s.setCookies(cookies)
+	}
+
+	if len(s.login.Steps) > 0 {
+		cookies, err := runLogin(s.login, browserPath, proxy, customHeaders)
+		if err != nil {
+			return nil, fmt.Errorf("login failed: %w", err)
+		}
+		s.setCookies(cookies)
+	}
+
+	return s, nil
+}
+
+// Jar 返回二次请求使用的 cookie jar，响应中的 Set-Cookie 也会更新到其中
+func (s *Session) Jar() http.CookieJar {
+	if s == nil {
+		return nil
+	}
+	return s.jar
+}
+
+// BrowserCookies 返回需要设置到无头浏览器中的 cookie
+func (s *Session) BrowserCookies() []*proto.NetworkCookieParam {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	params := make([]*proto.NetworkCookieParam, 0, len(s.cookies))
+	for _, c := range s.cookies {
+		p := &proto.NetworkCookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+		if !c.Expires.IsZero() {
+			p.Expires = proto.TimeSinceEpoch(c.Expires.Unix())
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// Generation 返回当前会话的版本号，检测到失效时把它传给 Refresh
+func (s *Session) Generation() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+// Expired 根据响应状态码、跳转后的最终 URL 和响应内容判断会话是否已经失效
+func (s *Session) Expired(status int, finalURL string, body string) bool {
+	if s == nil {
+		return false
+	}
+	if s.expiredStatus[status] {
+		return true
+	}
+	if s.expiredURL != nil && finalURL != "" && s.expiredURL.MatchString(finalURL) {
+		return true
+	}
+	return s.expiredBody != nil && body != "" && s.expiredBody.MatchString(body)
+}
+
+// Refresh 重新执行登录流程。generation 为检测到失效时的会话版本号：
+// 如果在此期间其他 goroutine 已经刷新过，就直接返回，避免并发请求同时触发多次登录。
+func (s *Session) Refresh(generation int) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generation != generation {
+		return nil
+	}
+	if len(s.login.Steps) == 0 {
+		return fmt.Errorf("session expired and no login flow is configured")
+	}
+	if s.refreshes >= maxRefreshes {
+		return fmt.Errorf("session expired again after %d re-logins", maxRefreshes)
+	}
+	s.refreshes++
+
+	log.Printf("[*] Session expired, re-running login flow (%d/%d)", s.refreshes, maxRefreshes)
+	cookies, err := runLogin(s.login, s.browserPath, s.proxy, s.customHeaders)
+	if err != nil {
+		return fmt.Errorf("re-login failed: %w", err)
+	}
+	s.setCookiesLocked(cookies)
+	s.generation++
+	return nil
+}
+
+// setCookies 将 cookie 同时写入 jar 和用于浏览器同步的列表
+func (s *Session) setCookies(cookies []*http.Cookie) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setCookiesLocked(cookies)
+}
+
+func (s *Session) setCookiesLocked(cookies []*http.Cookie) {
+	// 同名同域同路径的 cookie 以新的为准
+	index := make(map[string]int, len(s.cookies))
+	for i, c := range s.cookies {
+		index[cookieKey(c)] = i
+	}
+
+	for _, c := range cookies {
+		if i, ok := index[cookieKey(c)]; ok {
+			s.cookies[i] = c
+		} else {
+			index[cookieKey(c)] = len(s.cookies)
+			s.cookies = append(s.cookies, c)
+		}
+
+		// cookiejar 需要按 cookie 所属的站点写入；以 . 开头的是域名 cookie，其余为 host-only
+		host := strings.TrimPrefix(c.Domain, ".")
+		scheme := "http"
+		if c.Secure {
+			scheme = "https"
+		}
+		path := c.Path
+		if path == "" {
+			path = "/"
+		}
+		jarCookie := *c
+		if !strings.HasPrefix(c.Domain, ".") {
+			jarCookie.Domain = ""
+		}
+		s.jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: path}, []*http.Cookie{&jarCookie})
+	}
+}
+
+func cookieKey(c *http.Cookie) string {
+	return strings.TrimPrefix(c.Domain, ".") + "|" + c.Path + "|" + c.Name
+}
@@ -0,0 +1,400 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxChunkRounds 限制懒加载 chunk 的发现轮数（新 chunk 里可能还引用了其他 chunk）
+const MaxChunkRounds = 3
+
+var (
+	// webpack 运行时里拼接 chunk 文件名的函数，分组 1 为 chunkId 参数名，表达式从匹配结束处开始
+	webpackChunkFuncRegexes = []*regexp.Regexp{
+		// webpack 5: __webpack_require__.u = function(chunkId) { return ... }
+		regexp.MustCompile(`\.u\s*=\s*function\s*\(\s*([\w$]+)\s*\)\s*\{\s*(?://[^\n]*\n\s*)*return\s*`),
+		// webpack 5 (minified): r.u = e => ... / r.u = (e) => { return ... }
+		regexp.MustCompile(`\.u\s*=\s*\(?\s*([\w$]+)\s*\)?\s*=>\s*(?:\{\s*(?://[^\n]*\n\s*)*return\s*)?`),
+		// webpack 4: function jsonpScriptSrc(chunkId) { return __webpack_require__.p + ... }
+		regexp.MustCompile(`function\s*[\w$]*\s*\(\s*([\w$]+)\s*\)\s*\{\s*return\s+[\w$]+\.p\s*\+\s*`),
+	}
+	// webpack 的 publicPath：__webpack_require__.p = "/static/"
+	webpackPublicPathRegex = regexp.MustCompile(`\.p\s*=\s*["']([^"']*)["']`)
+	// chunk 映射表 {12:"abc","chunk-x":"def"} 中的键值对
+	chunkMapEntryRegex = regexp.MustCompile(`["']?([\w$-]+)["']?\s*:\s*["']([^"']*)["']`)
+
+	// Vite: const __vite__mapDeps=(i,m=__vite__mapDeps,d=(m.f||(m.f=["assets/a.js",...])))=>i.map(i=>d[i]);
+	viteMapDepsRegex = regexp.MustCompile(`__vite__mapDeps\s*=[^\[]*\[([^\]]*)\]`)
+
+	// Next.js: _buildManifest.js 中的 chunk 路径与路由、_ssgManifest.js 中的 SSG 路由、页面中的 buildId
+	nextStaticChunkRegex = regexp.MustCompile(`["'](static/[^"']+\.js)["']`)
+	nextRouteRegex       = regexp.MustCompile(`["'](/[^"'\s]*)["']\s*:\s*\[`)
+	nextSSGSetRegex      = regexp.MustCompile(`__SSG_MANIFEST\s*=\s*new\s+Set\s*\(\s*\[([^\]]*)\]`)
+	nextBuildIDRegex     = regexp.MustCompile(`"buildId"\s*:\s*"([^"]+)"`)
+	nextManifestRegex    = regexp.MustCompile(`/_next/static/([^/]+)/_(?:build|ssg)Manifest\.js$`)
+
+	// JS 字符串字面量
+	jsStringRegex = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"|'((?:[^'\\]|\\.)*)'`)
+)
+
+// DiscoverChunks 从单个 JS/HTML 内容中还原懒加载 chunk 的绝对 URL，scriptURL 为该内容自身的 URL。
+func DiscoverChunks(body string, scriptURL string) []string {
+	script, err := url.Parse(scriptURL)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var chunks []string
+	add := func(base *url.URL, rel string) {
+		u := resolveURL(base, rel)
+		if u == nil {
+			return
+		}
+		s := u.String()
+		if _, ok := seen[s]; ok {
+			return
+		}
+		seen[s] = struct{}{}
+		chunks = append(chunks, s)
+	}
+
+	// 1) webpack
+	for _, rel := range webpackChunkPaths(body) {
+		add(webpackBase(body, script, rel), rel)
+	}
+
+	// 2) Vite
+	for _, m := range viteMapDepsRegex.FindAllStringSubmatch(body, -1) {
+		for _, dep := range jsStrings(m[1]) {
+			if strings.HasSuffix(dep, ".js") {
+				add(guessAssetBase(script, dep), dep)
+			}
+		}
+	}
+
+	// 3) Next.js
+	for _, s := range nextChunkURLs(body, script) {
+		add(script, s)
+	}
+
+	return chunks
+}
+
+// webpackChunkPaths 解析 webpack 运行时中 chunk 文件名的拼接表达式，
+// 枚举映射表中的所有 chunkId，返回相对 publicPath 的 chunk 路径。
+func webpackChunkPaths(body string) []string {
+	var paths []string
+	for _, re := range webpackChunkFuncRegexes {
+		for _, loc := range re.FindAllStringSubmatchIndex(body, -1) {
+			param := body[loc[2]:loc[3]]
+			expr := readJSExpression(body[loc[1]:])
+			if !strings.Contains(expr, ".js") {
+				// 例如 miniCssF 拼接的是 CSS 文件名
+				continue
+			}
+			paths = append(paths, evalChunkExpression(expr, param)...)
+		}
+	}
+	return paths
+}
+
+// chunkPart 表示 chunk 文件名拼接表达式中的一段
+type chunkPart struct {
+	literal  string            // 字符串常量
+	lookup   map[string]string // {id:value}[chunkId] 映射表
+	fallback bool              // ({...}[chunkId]||chunkId)，映射表中没有时使用 chunkId
+	isID     bool              // chunkId 本身
+}
+
+// evalChunkExpression 对形如 "static/js/"+({1:"vendors"}[e]||e)+"."+{1:"abc"}[e]+".chunk.js" 的表达式
+// 枚举映射表中出现过的所有 chunkId 并计算出对应的文件名
+func evalChunkExpression(expr string, param string) []string {
+	var parts []chunkPart
+	ids := make(map[string]struct{})
+	var order []string
+
+	for _, raw := range splitTopLevel(expr, '+') {
+		p := stripParens(strings.TrimSpace(raw))
+		switch {
+		case p == param:
+			parts = append(parts, chunkPart{isID: true})
+		case isJSString(p):
+			parts = append(parts, chunkPart{literal: unquoteJS(p)})
+		case strings.HasPrefix(p, "{"):
+			mapEnd := strings.LastIndex(p, "}[")
+			if mapEnd == -1 {
+				continue
+			}
+			lookup := make(map[string]string)
+			for _, m := range chunkMapEntryRegex.FindAllStringSubmatch(p[1:mapEnd], -1) {
+				lookup[m[1]] = m[2]
+				if _, ok := ids[m[1]]; !ok {
+					ids[m[1]] = struct{}{}
+					order = append(order, m[1])
+				}
+			}
+			rest := strings.ReplaceAll(p[mapEnd+1:], " ", "")
+			parts = append(parts, chunkPart{
+				lookup:   lookup,
+				fallback: strings.Contains(rest, "||"+param),
+			})
+		default:
+			// publicPath（r.p）等无法静态求值的部分忽略
+		}
+	}
+
+	var paths []string
+	for _, id := range order {
+		var sb strings.Builder
+		ok := true
+		for _, part := range parts {
+			switch {
+			case part.isID:
+				sb.WriteString(id)
+			case part.lookup != nil:
+				v, exists := part.lookup[id]
+				if !exists {
+					if !part.fallback {
+						ok = false
+					}
+					v = id
+				}
+				sb.WriteString(v)
+			default:
+				sb.WriteString(part.literal)
+			}
+		}
+		if ok {
+			paths = append(paths, sb.String())
+		}
+	}
+	return paths
+}
+
+// webpackBase 返回 chunk 路径的解析基准：优先使用运行时中写死的 publicPath，
+// 否则（publicPath 为 auto 或未找到）根据脚本自身的路径推断
+func webpackBase(body string, script *url.URL, rel string) *url.URL {
+	if m := webpackPublicPathRegex.FindStringSubmatch(body); m != nil && m[1] != "" && m[1] != "auto" {
+		if base := resolveURL(script, m[1]); base != nil {
+			return base
+		}
+	}
+	return guessAssetBase(script, rel)
+}
+
+// guessAssetBase 推断相对资源路径的基准：如果脚本路径中包含 rel 的目录部分
+// （如脚本 /static/js/main.js 与 rel static/js/1.chunk.js），则以该目录之前的部分为基准，否则以脚本所在目录为基准
+func guessAssetBase(script *url.URL, rel string) *url.URL {
+	rel = strings.TrimPrefix(rel, "./")
+	if slash := strings.LastIndex(rel, "/"); slash != -1 && !strings.HasPrefix(rel, "/") {
+		dir := "/" + rel[:slash+1]
+		if idx := strings.Index(script.Path, dir); idx != -1 {
+			base := *script
+			base.Path = script.Path[:idx+1]
+			base.RawQuery = ""
+			return &base
+		}
+	}
+	return script
+}
+
+// nextChunkURLs 从 Next.js 的 _buildManifest.js、_ssgManifest.js 和页面中的 __NEXT_DATA__ 还原链接
+func nextChunkURLs(body string, script *url.URL) []string {
+	var urls []string
+
+	// 以 /_next/ 目录为基准（basePath 部署时该目录不在根路径下）
+	nextRoot := "/_next/"
+	if idx := strings.Index(script.Path, "/_next/"); idx != -1 {
+		nextRoot = script.Path[:idx] + "/_next/"
+	}
+	basePath := strings.TrimSuffix(nextRoot, "/_next/")
+
+	// 1) 页面中的 buildId => 两个 manifest 文件
+	if m := nextBuildIDRegex.FindStringSubmatch(body); m != nil {
+		urls = append(urls,
+			nextRoot+"static/"+m[1]+"/_buildManifest.js",
+			nextRoot+"static/"+m[1]+"/_ssgManifest.js",
+		)
+	}
+
+	m := nextManifestRegex.FindStringSubmatch(script.Path)
+	if m == nil {
+		return urls
+	}
+	buildID := m[1]
+
+	// 2) _buildManifest.js：所有页面 chunk 与不含动态参数的路由
+	if strings.Contains(body, "__BUILD_MANIFEST") {
+		for _, c := range nextStaticChunkRegex.FindAllStringSubmatch(body, -1) {
+			urls = append(urls, nextRoot+c[1])
+		}
+		for _, r := range nextRouteRegex.FindAllStringSubmatch(body, -1) {
+			route := unescapeJS(r[1])
+			if strings.ContainsAny(route, "[]") || strings.HasPrefix(route, "/_") {
+				continue
+			}
+			urls = append(urls, basePath+route)
+		}
+	}
+
+	// 3) _ssgManifest.js：SSG 路由对应的页面和 /_next/data/<buildId>/<route>.json
+	if set := nextSSGSetRegex.FindStringSubmatch(body); set != nil {
+		for _, route := range jsStrings(set[1]) {
+			if strings.ContainsAny(route, "[]") {
+				continue
+			}
+			dataPath := route
+			if dataPath == "/" {
+				dataPath = "/index"
+			}
+			urls = append(urls,
+				basePath+route,
+				nextRoot+"data/"+buildID+dataPath+".json",
+			)
+		}
+	}
+
+	return urls
+}
+
+// readJSExpression 读取一个 JS 表达式，直到遇到最外层的 ; , ) } 为止
+func readJSExpression(s string) string {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			if depth == 0 {
+				return s[:i]
+			}
+			depth--
+		case ';', ',', '\n':
+			if depth == 0 {
+				return s[:i]
+			}
+		}
+	}
+	return s
+}
+
+// splitTopLevel 按最外层的 sep 拆分表达式（忽略括号和字符串内部的 sep）
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// stripParens 去掉包裹整个表达式的括号
+func stripParens(s string) string {
+	for wrappedInParens(s) {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	return s
+}
+
+// wrappedInParens 判断开头的 ( 是否与结尾的 ) 配对，(a)+(b) 这样首尾括号各自配对的表达式返回 false
+func wrappedInParens(s string) bool {
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return false
+	}
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			depth--
+			if depth == 0 && i < len(s)-1 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// isJSString 判断表达式是否是单个字符串字面量
+func isJSString(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	q := s[0]
+	return (q == '"' || q == '\'') && s[len(s)-1] == q
+}
+
+// unquoteJS 去掉字符串字面量的引号并处理转义
+func unquoteJS(s string) string {
+	return unescapeJS(s[1 : len(s)-1])
+}
+
+// unescapeJS 处理 JS 字符串中常见的转义（如 \/ 和 \u002F）
+func unescapeJS(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	if v, err := strconv.Unquote(`"` + strings.ReplaceAll(s, `"`, `\"`) + `"`); err == nil {
+		return v
+	}
+	return strings.ReplaceAll(s, `\/`, `/`)
+}
+
+// jsStrings 返回一段 JS 代码中的所有字符串字面量
+func jsStrings(s string) []string {
+	var out []string
+	for _, m := range jsStringRegex.FindAllStringSubmatch(s, -1) {
+		out = append(out, unescapeJS(m[1]+m[2]))
+	}
+	return out
+}
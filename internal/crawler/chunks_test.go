@@ -0,0 +1,156 @@
+package crawler
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEvalChunkExpression(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		param string
+		want  []string
+	}{
+		{
+			name:  "hash map",
+			expr:  `"static/js/"+e+"."+{12:"a1b2",34:"c3d4"}[e]+".chunk.js"`,
+			param: "e",
+			want:  []string{"static/js/12.a1b2.chunk.js", "static/js/34.c3d4.chunk.js"},
+		},
+		{
+			name:  "name map with fallback",
+			expr:  `"js/"+({5:"vendors"}[e]||e)+"."+{5:"aaa",7:"bbb"}[e]+".js"`,
+			param: "e",
+			want:  []string{"js/vendors.aaa.js", "js/7.bbb.js"},
+		},
+		{
+			name:  "name map without fallback",
+			expr:  `"js/"+{5:"vendors"}[e]+"."+{5:"aaa",7:"bbb"}[e]+".js"`,
+			param: "e",
+			want:  []string{"js/vendors.aaa.js"},
+		},
+		{
+			name:  "parenthesized parts",
+			expr:  `("js/")+(e)+"."+({1:"abc"}[e])+".js"`,
+			param: "e",
+			want:  []string{"js/1.abc.js"},
+		},
+		{
+			name:  "public path ignored",
+			expr:  `r.p+"static/"+{"chunk-x":"def"}[e]+".js"`,
+			param: "e",
+			want:  []string{"static/def.js"},
+		},
+		{
+			name:  "no map",
+			expr:  `"js/"+e+".js"`,
+			param: "e",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := evalChunkExpression(tt.expr, tt.param)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("evalChunkExpression() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripParens(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`e`, `e`},
+		{`(e)`, `e`},
+		{`(( e ))`, `e`},
+		{`(a)+(b)`, `(a)+(b)`},
+		{`({1:"a"}[e]||e)`, `{1:"a"}[e]||e`},
+		{`(")")`, `")"`},
+		{`(a))`, `(a))`},
+	}
+	for _, tt := range tests {
+		if got := stripParens(tt.in); got != tt.want {
+			t.Errorf("stripParens(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		scriptURL string
+		want      []string
+	}{
+		{
+			name:      "webpack 5 with public path",
+			body:      `r.p="/static/";r.u=e=>"js/"+e+"."+{12:"a1b2",34:"c3d4"}[e]+".chunk.js";`,
+			scriptURL: "https://www.example.com/static/js/main.js",
+			want: []string{
+				"https://www.example.com/static/js/12.a1b2.chunk.js",
+				"https://www.example.com/static/js/34.c3d4.chunk.js",
+			},
+		},
+		{
+			name:      "webpack 5 auto public path",
+			body:      `__webpack_require__.u = function(chunkId) { return "static/js/" + chunkId + "." + {"7":"ffee"}[chunkId] + ".chunk.js"; }`,
+			scriptURL: "https://www.example.com/app/static/js/main.js",
+			want:      []string{"https://www.example.com/app/static/js/7.ffee.chunk.js"},
+		},
+		{
+			name:      "webpack css chunks ignored",
+			body:      `r.miniCssF=e=>"css/"+e+"."+{1:"aa"}[e]+".css";`,
+			scriptURL: "https://www.example.com/main.js",
+			want:      nil,
+		},
+		{
+			name:      "vite map deps",
+			body:      `const __vite__mapDeps=(i,m=__vite__mapDeps,d=(m.f||(m.f=["assets/Home-1a2b.js","assets/Home-3c4d.css"])))=>i.map(i=>d[i]);`,
+			scriptURL: "https://www.example.com/assets/index-9f8e.js",
+			want:      []string{"https://www.example.com/assets/Home-1a2b.js"},
+		},
+		{
+			name:      "next build id",
+			body:      `<script id="__NEXT_DATA__" type="application/json">{"buildId":"abc123"}</script>`,
+			scriptURL: "https://www.example.com/",
+			want: []string{
+				"https://www.example.com/_next/static/abc123/_buildManifest.js",
+				"https://www.example.com/_next/static/abc123/_ssgManifest.js",
+			},
+		},
+		{
+			name:      "next build manifest",
+			body:      `self.__BUILD_MANIFEST={"/about":["static/chunks/pages/about-1.js"],"/post/[id]":["static/chunks/pages/post-2.js"]};`,
+			scriptURL: "https://www.example.com/_next/static/abc123/_buildManifest.js",
+			want: []string{
+				"https://www.example.com/_next/static/chunks/pages/about-1.js",
+				"https://www.example.com/_next/static/chunks/pages/post-2.js",
+				"https://www.example.com/about",
+			},
+		},
+		{
+			name:      "next ssg manifest",
+			body:      `self.__SSG_MANIFEST=new Set(["/","/blog"]);`,
+			scriptURL: "https://www.example.com/_next/static/abc123/_ssgManifest.js",
+			want: []string{
+				"https://www.example.com/",
+				"https://www.example.com/_next/data/abc123/index.json",
+				"https://www.example.com/blog",
+				"https://www.example.com/_next/data/abc123/blog.json",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiscoverChunks(tt.body, tt.scriptURL)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("DiscoverChunks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
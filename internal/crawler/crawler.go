@@ -1,107 +1,57 @@
 package crawler
 
 import (
+	"SecureJS/internal/auth"
+	"SecureJS/internal/parser"
+	"SecureJS/internal/scope"
 	"SecureJS/internal/utils"
+	"encoding/base64"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/launcher"
 	"github.com/go-rod/rod/lib/proto"
-
+	"github.com/ysmood/gson"
 )
 
 type CrawlResult struct {
 	URL         string
 	AllRequests []string
+	Responses   []*parser.ParseResult // 浏览器加载过程中捕获到的响应内容
 	Error       error
 }
 
+// 捕获响应内容时 Chrome 的网络缓冲区大小
+const (
+	maxCaptureTotalBuffer    = 200 << 20
+	maxCaptureResourceBuffer = 20 << 20
+)
+
 // -----------------------------------------------------------
-// 并发爬取多个链接
+// 对外的接口：用无头浏览器加载单个页面
 // -----------------------------------------------------------
-func crawlAll(urls []string, concurrency int, browserPath string, customHeaders []string, proxy string) ([]*CrawlResult, error) {
-	if len(urls) == 0 {
-		return nil, fmt.Errorf("no URLs provided")
-	}
-	if concurrency <= 0 {
-		concurrency = 1
-	}
-
-	var chromePath string
-	if browserPath != "" {
-		chromePath = browserPath
-	} else {
-		chromePath = launcher.NewBrowser().MustGet()
-	}
-
-	launch := launcher.New().
-	Bin(chromePath).
-	Headless(true).
-	Set("ignore-certificate-errors").
-	Set("disable-blink-features", "AutomationControlled").
-	Set("disable-infobars")
-
-	if proxy != "" {
-		launch = launch.Proxy(proxy)
-	}
-
-	u := launch.MustLaunch()
-
-	browser := rod.New().ControlURL(u).MustConnect()
-	defer func() {
-		if err := browser.Close(); err != nil {
-			log.Printf("[!] failed to close browser: %v\n", err)
-		}
-	}()
-
-	resultChan := make(chan *CrawlResult, len(urls))
-	sem := make(chan struct{}, concurrency)
-	var wg sync.WaitGroup
-
-	for _, targetURL := range urls {
-		wg.Add(1)
-		go func(url string) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			// 最大重试次数，可自行调整
-			const maxRetry = 3
-			res, err := fetchOneURLWithRetry(browser, url, maxRetry, customHeaders)
-			if err != nil {
-				resultChan <- &CrawlResult{URL: url, Error: err}
-				return
-			}
-			resultChan <- res
-		}(targetURL)
-	}
-
-	wg.Wait()
-	close(resultChan)
-
-	var results []*CrawlResult
-	for r := range resultChan {
-		results = append(results, r)
-	}
-
-	return results, nil
+// CrawlPage 在 browser 中加载 url（失败时重试），返回加载过程中发出的请求和捕获到的响应内容，
+// 捕获到的响应无需再重新请求，其中 JS 的 source map 通过 client 获取。多个 goroutine 可以共用同一个 browser 并发调用。
+func CrawlPage(browser *rod.Browser, url string, client *parser.Client, customHeaders []string, sc *scope.Scope, sess *auth.Session) (*CrawlResult, error) {
+	// 最大重试次数，可自行调整
+	const maxRetry = 3
+	return fetchOneURLWithRetry(browser, url, maxRetry, client, customHeaders, sc, sess)
 }
 
 // -----------------------------------------------------------
 // 带重试的抓取逻辑
 // -----------------------------------------------------------
-func fetchOneURLWithRetry(browser *rod.Browser, url string, maxAttempts int, customHeaders []string) (*CrawlResult, error) {
+func fetchOneURLWithRetry(browser *rod.Browser, url string, maxAttempts int, client *parser.Client, customHeaders []string, sc *scope.Scope, sess *auth.Session) (*CrawlResult, error) {
 	var lastErr error
 	baseTime := 20 * time.Second
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		currentTimeout := time.Duration(attempt) * baseTime
 
-		result, err := tryFetchOneURL(browser, url, currentTimeout, customHeaders)
+		result, err := tryFetchOneURL(browser, url, currentTimeout, client, customHeaders, sc, sess)
 		if err == nil {
 			return result, nil
 		}
@@ -121,7 +71,7 @@ func fetchOneURLWithRetry(browser *rod.Browser, url string, maxAttempts int, cus
 // -----------------------------------------------------------
 // 单次访问逻辑：在已有 page 上使用 stealth.Inject(page)
 // -----------------------------------------------------------
-func tryFetchOneURL(browser *rod.Browser, url string, timeout time.Duration, customHeaders []string) (*CrawlResult, error) {
+func tryFetchOneURL(browser *rod.Browser, url string, timeout time.Duration, client *parser.Client, customHeaders []string, sc *scope.Scope, sess *auth.Session) (*CrawlResult, error) {
 	page := browser.MustPage("")
 	defer page.Close()
 
@@ -144,7 +94,7 @@ func tryFetchOneURL(browser *rod.Browser, url string, timeout time.Duration, cus
 	if len(customHeaders) > 0 {
 		// 1) 准备一个 []string 来存储 "Key", "Value" 这种键值对
 		var headerPairs []string
-	
+
 		// 2) 遍历 -H 参数里传来的 "Key: Value" 格式字符串
 		for _, h := range customHeaders {
 			parts := strings.SplitN(h, ":", 2)
@@ -155,33 +105,88 @@ func tryFetchOneURL(browser *rod.Browser, url string, timeout time.Duration, cus
 				headerPairs = append(headerPairs, key, val)
 			}
 		}
-	
+
 		// 4) 调用 page.SetExtraHeaders(...)
 		//    注意要用变长参数传进去，所以是 headerPairs...
 		page.SetExtraHeaders(headerPairs)
 	}
 
+	// 设置登录会话的 cookie（注意 SetCookies(nil) 会清空 cookie，所以只在有会话时设置）
+	if cookies := sess.BrowserCookies(); len(cookies) > 0 {
+		if err := page.SetCookies(cookies); err != nil {
+			return nil, fmt.Errorf("failed to set session cookies: %w", err)
+		}
+	}
+	generation := sess.Generation()
+
 	// 设置超时
 	page = page.Timeout(timeout)
 
+	// 开启 Network 域并调大缓冲区，保证页面空闲后仍能通过 Network.getResponseBody 取回响应内容
+	err = proto.NetworkEnable{
+		MaxTotalBufferSize:    gson.Int(maxCaptureTotalBuffer),
+		MaxResourceBufferSize: gson.Int(maxCaptureResourceBuffer),
+	}.Call(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable network: %w", err)
+	}
+
+	// 拦截范围外主机的请求，浏览器加载页面时不会访问第三方主机。
+	// include / exclude 只用于筛选收集的链接，不拦截页面自身和它加载的资源，否则页面无法正常加载
+	if sc != nil {
+		router := page.HijackRequests()
+		err = router.Add("*", "", func(ctx *rod.Hijack) {
+			if !sc.AllowHost(ctx.Request.URL().String()) {
+				ctx.Response.Fail(proto.NetworkErrorReasonBlockedByClient)
+				return
+			}
+			ctx.ContinueRequest(&proto.FetchContinueRequest{})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to hijack requests: %w", err)
+		}
+		go router.Run()
+		defer func() { _ = router.Stop() }()
+	}
+
 	loadedMap := make(map[string]bool)
 	loadedMap[url] = true
 
-	stop := page.EachEvent(func(e *proto.NetworkRequestWillBeSent) {
-		reqURL := e.Request.URL
-		lowerURL := strings.ToLower(reqURL)
+	// 需要捕获响应内容的请求：requestId => 响应信息
+	responses := make(map[proto.NetworkRequestID]*proto.NetworkResponse)
+	var finished []proto.NetworkRequestID
+	var document *proto.NetworkResponse // 主文档的响应，用于判断登录会话是否失效
 
-		// 过滤
-		if utils.Skip(lowerURL) {
-			return
-		}
-		if utils.HasSkipExtension(lowerURL) {
+	// 事件在单独的 goroutine 中处理，页面空闲后通过 cancel 结束监听
+	eventPage, cancelEvents := page.WithCancel()
+	wait := eventPage.EachEvent(func(e *proto.NetworkRequestWillBeSent) {
+		reqURL := e.Request.URL
+		if !ShouldCollect(reqURL, sc) {
 			return
 		}
 
 		normalized := strings.TrimSuffix(reqURL, "/")
 		loadedMap[normalized] = true
+	}, func(e *proto.NetworkResponseReceived) {
+		if document == nil && e.Type == proto.NetworkResourceTypeDocument {
+			document = e.Response
+		}
+		if !ShouldCollect(e.Response.URL, sc) {
+			return
+		}
+		responses[e.RequestID] = e.Response
+	}, func(e *proto.NetworkLoadingFinished) {
+		finished = append(finished, e.RequestID)
 	})
+	eventsDone := make(chan struct{})
+	go func() {
+		defer close(eventsDone)
+		wait()
+	}()
+	stop := func() {
+		cancelEvents()
+		<-eventsDone
+	}
 
 	// 导航
 	if err := page.Navigate(url); err != nil {
@@ -202,32 +207,94 @@ func tryFetchOneURL(browser *rod.Browser, url string, timeout time.Duration, cus
 		allRequests = append(allRequests, r)
 	}
 
+	// 取回已完成请求的响应内容
+	var captured []*parser.ParseResult
+	for _, id := range finished {
+		resp, ok := responses[id]
+		if !ok {
+			continue
+		}
+		// 重定向、预检请求等没有响应体，取不到时交给后续的 HTTP 请求兜底
+		if resp.Status >= 300 && resp.Status < 400 {
+			continue
+		}
+		pr, err := captureResponse(page, id, resp, client)
+		if err != nil {
+			continue
+		}
+		captured = append(captured, pr)
+	}
+
+	// 会话失效：重新登录后返回错误，由 fetchOneURLWithRetry 使用新的 cookie 重试
+	if document != nil {
+		var documentBody string
+		for _, pr := range captured {
+			if pr.URL == strings.TrimSuffix(document.URL, "/") {
+				documentBody = pr.Body
+				break
+			}
+		}
+		if sess.Expired(document.Status, document.URL, documentBody) {
+			if err := sess.Refresh(generation); err != nil {
+				log.Printf("[!] %s: %v", url, err)
+			} else {
+				return nil, fmt.Errorf("session expired while loading %s, logged in again", url)
+			}
+		}
+	}
+
 	return &CrawlResult{
 		URL:         url,
 		AllRequests: allRequests,
+		Responses:   captured,
 	}, nil
 }
 
-// -----------------------------------------------------------
-// 对外的接口，用于收集
-// -----------------------------------------------------------
-func CollectLinks(urls []string, threads int, uniqueLinks map[string]struct{}, toParse *[]string, browserPath string, customHeaders []string, proxy string) error {
-	results, err := crawlAll(urls, threads, browserPath, customHeaders, proxy)
+// ShouldCollect 判断发现的链接是否需要收集：过滤掉黑名单、静态资源后缀和不在扫描范围内的链接
+func ShouldCollect(reqURL string, sc *scope.Scope) bool {
+	lowerURL := strings.ToLower(reqURL)
+
+	// 过滤
+	if utils.Skip(lowerURL) {
+		return false
+	}
+	if utils.HasSkipExtension(lowerURL) {
+		return false
+	}
+	// 不在扫描范围内的链接不收集
+	return sc.Allow(reqURL)
+}
+
+// captureResponse 通过 Network.getResponseBody 取回浏览器已加载的响应内容，并转换为 ParseResult
+func captureResponse(page *rod.Page, id proto.NetworkRequestID, resp *proto.NetworkResponse, client *parser.Client) (*parser.ParseResult, error) {
+	res, err := proto.NetworkGetResponseBody{RequestID: id}.Call(page)
 	if err != nil {
-		return fmt.Errorf("failed to crawl: %v", err)
+		return nil, err
 	}
 
-	for _, result := range results {
-		if result.Error != nil {
-			fmt.Printf("[!] URL: %s, Error: %v\n", result.URL, result.Error)
-			continue
-		}
-		for _, reqURL := range result.AllRequests {
-			if _, exists := uniqueLinks[reqURL]; !exists {
-				uniqueLinks[reqURL] = struct{}{}
-				*toParse = append(*toParse, reqURL)
-			}
+	body := res.Body
+	if res.Base64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode body: %w", err)
 		}
+		body = string(decoded)
 	}
-	return nil
-}
\ No newline at end of file
+
+	header := make(http.Header)
+	for k, v := range resp.Headers {
+		header.Set(k, v.Str())
+	}
+
+	// 浏览器返回的文本内容已按资源的字符集解码为 UTF-8，这里只记录浏览器检测到的类型和字符集
+	pr := &parser.ParseResult{
+		URL:         strings.TrimSuffix(resp.URL, "/"),
+		StatusCode:  resp.Status,
+		Header:      header,
+		Body:        strings.TrimSpace(body),
+		ContentType: resp.MIMEType,
+		Encoding:    strings.ToLower(resp.Charset),
+	}
+	client.ResolveSourceMap(pr, header)
+	return pr, nil
+}
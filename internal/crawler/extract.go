@@ -0,0 +1,179 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// 绝对链接和协议相对链接（//cdn.example.com/x.js）
+	absoluteURLRegex = regexp.MustCompile(`https?://[^\s"'<>\x60]+`)
+	protoRelRegex    = regexp.MustCompile(`["'\x60](//[a-zA-Z0-9][^\s"'<>\x60]*)["'\x60]`)
+
+	// HTML 标签及其属性
+	baseTagRegex   = regexp.MustCompile(`(?is)<base\b[^>]*>`)
+	scriptTagRegex = regexp.MustCompile(`(?is)<script\b[^>]*>`)
+	linkTagRegex   = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	anchorTagRegex = regexp.MustCompile(`(?is)<(?:a|iframe|frame)\b[^>]*>`)
+	attrRegex      = regexp.MustCompile(`(?is)\b([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+	// JS 中的动态引用：import("...")、new Worker("...")、new SharedWorker("...")、importScripts("...")
+	dynamicImportRegex = regexp.MustCompile(`\bimport\s*\(\s*["'\x60]([^"'\x60]+)["'\x60]`)
+	staticImportRegex  = regexp.MustCompile(`\b(?:import|export)\b[^;"'\x60]*?\bfrom\s*["']([^"']+)["']`)
+	workerRegex        = regexp.MustCompile(`\bnew\s+(?:Shared)?Worker\s*\(\s*(?:new\s+URL\s*\(\s*)?["'\x60]([^"'\x60]+)["'\x60]`)
+	importScriptsRegex = regexp.MustCompile(`\bimportScripts\s*\(\s*["']([^"']+)["']`)
+
+	// JS 中以引号包裹、以 / 或 ./ ../ 开头的 .js 路径
+	relScriptRegex = regexp.MustCompile(`["'\x60]((?:\.{1,2}/|/)[^\s"'\x60<>]*?\.js(?:\?[^\s"'\x60<>]*)?)["'\x60]`)
+)
+
+// preloadRels 是会被当作脚本引用的 <link rel> 取值
+var preloadRels = map[string]bool{
+	"preload":       true,
+	"modulepreload": true,
+	"prefetch":      true,
+}
+
+// ExtractLinks 从 HTML/JS 内容中提取链接，并将相对链接、协议相对链接解析为绝对 URL。
+// HTML 中的相对链接以 <base href> 为基准（没有时以 pageURL 为基准），
+// 会读取 <script src>、<link rel=preload|modulepreload href>、<a href>、<iframe src>、
+// import()、new Worker() 等引用。返回的链接已去重，保持首次出现的顺序。
+func ExtractLinks(body string, pageURL string) []string {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		page = nil
+	}
+	base := page
+
+	// 1) <base href> 改变页面中相对链接的基准
+	if tag := baseTagRegex.FindString(body); tag != "" {
+		if href := tagAttrs(tag)["href"]; href != "" {
+			if b := resolveURL(page, href); b != nil {
+				base = b
+			}
+		}
+	}
+
+	seen := make(map[string]struct{})
+	var links []string
+	add := func(ref *url.URL, raw string) {
+		u := resolveURL(ref, raw)
+		if u == nil {
+			return
+		}
+		s := u.String()
+		if _, ok := seen[s]; ok {
+			return
+		}
+		seen[s] = struct{}{}
+		links = append(links, s)
+	}
+
+	// 2) 绝对链接与协议相对链接
+	for _, m := range absoluteURLRegex.FindAllString(body, -1) {
+		add(page, trimURLPunct(m))
+	}
+	for _, m := range protoRelRegex.FindAllStringSubmatch(body, -1) {
+		add(page, m[1])
+	}
+
+	// 3) HTML 标签
+	for _, tag := range scriptTagRegex.FindAllString(body, -1) {
+		if src := tagAttrs(tag)["src"]; src != "" {
+			add(base, src)
+		}
+	}
+	for _, tag := range linkTagRegex.FindAllString(body, -1) {
+		attrs := tagAttrs(tag)
+		for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+			if preloadRels[rel] && attrs["href"] != "" {
+				add(base, attrs["href"])
+				break
+			}
+		}
+	}
+	for _, tag := range anchorTagRegex.FindAllString(body, -1) {
+		attrs := tagAttrs(tag)
+		if attrs["href"] != "" {
+			add(base, attrs["href"])
+		}
+		if attrs["src"] != "" {
+			add(base, attrs["src"])
+		}
+	}
+
+	// 4) JS 中的引用，对 JS 文件而言 base 就是脚本自身的 URL
+	for _, re := range []*regexp.Regexp{dynamicImportRegex, staticImportRegex, workerRegex, importScriptsRegex, relScriptRegex} {
+		for _, m := range re.FindAllStringSubmatch(body, -1) {
+			add(base, m[1])
+		}
+	}
+
+	return links
+}
+
+// closingBrackets 是链接末尾的右括号及对应的左括号
+var closingBrackets = map[byte]string{')': "(", ']': "[", '}': "{"}
+
+// trimURLPunct 去掉文本中的绝对链接末尾的标点，如 (见 https://a.com/x.js)、https://a.com/x.js; 中的 ) 和 ;。
+// 右括号只在链接中没有与之配对的左括号时去掉，保留 https://en.wikipedia.org/wiki/Foo_(bar) 这样的链接
+func trimURLPunct(s string) string {
+	for s != "" {
+		last := s[len(s)-1]
+		switch {
+		case strings.IndexByte(".,;:!?", last) >= 0:
+			s = s[:len(s)-1]
+		case closingBrackets[last] != "" && strings.Count(s, closingBrackets[last]) < strings.Count(s, string(last)):
+			s = s[:len(s)-1]
+		default:
+			return s
+		}
+	}
+	return s
+}
+
+// tagAttrs 解析单个 HTML 开始标签中的属性，属性名统一为小写
+func tagAttrs(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrRegex.FindAllStringSubmatch(tag, -1) {
+		name := strings.ToLower(m[1])
+		if _, exists := attrs[name]; exists {
+			continue
+		}
+		attrs[name] = strings.TrimSpace(m[2] + m[3] + m[4])
+	}
+	return attrs
+}
+
+// resolveURL 以 ref 为基准解析 raw，只返回 http/https 链接；
+// javascript:、mailto:、data:、纯锚点以及模板占位符等无法请求的引用返回 nil。
+func resolveURL(ref *url.URL, raw string) *url.URL {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.HasPrefix(raw, "#") {
+		return nil
+	}
+	// 模板占位符（${...}、{{...}}）不是真实链接
+	if strings.Contains(raw, "${") || strings.Contains(raw, "{{") {
+		return nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	if !u.IsAbs() {
+		if ref == nil {
+			return nil
+		}
+		u = ref.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	if u.Host == "" {
+		return nil
+	}
+	u.Fragment = ""
+	return u
+}
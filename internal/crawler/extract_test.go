@@ -0,0 +1,104 @@
+package crawler
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractLinks(t *testing.T) {
+	const page = "https://www.example.com/app/index.html"
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "absolute url in text",
+			body: `see https://api.example.com/v1/users for details`,
+			want: []string{"https://api.example.com/v1/users"},
+		},
+		{
+			name: "trailing punctuation",
+			body: `(https://a.example.com/x.js), https://b.example.com/y.js; https://c.example.com/z.js. https://d.example.com/w.js:`,
+			want: []string{
+				"https://a.example.com/x.js",
+				"https://b.example.com/y.js",
+				"https://c.example.com/z.js",
+				"https://d.example.com/w.js",
+			},
+		},
+		{
+			name: "balanced parentheses",
+			body: `https://en.wikipedia.org/wiki/Foo_(bar).`,
+			want: []string{"https://en.wikipedia.org/wiki/Foo_(bar)"},
+		},
+		{
+			name: "protocol relative",
+			body: `var s = "//cdn.example.com/lib.js";`,
+			want: []string{"https://cdn.example.com/lib.js"},
+		},
+		{
+			name: "script and preload",
+			body: `<script src="main.js"></script><link rel="modulepreload" href="/vendor.js"><link rel="stylesheet" href="/a.css">`,
+			want: []string{"https://www.example.com/app/main.js", "https://www.example.com/vendor.js"},
+		},
+		{
+			name: "base href",
+			body: `<base href="/static/"><script src="main.js"></script>`,
+			want: []string{"https://www.example.com/static/main.js"},
+		},
+		{
+			name: "anchor and iframe",
+			body: `<a href="../login#top">x</a><iframe src='frame.html'></iframe>`,
+			want: []string{"https://www.example.com/login", "https://www.example.com/app/frame.html"},
+		},
+		{
+			name: "js references",
+			body: `import("./chunk.js");import a from "../lib/a.js";new Worker(new URL("./w.js", import.meta.url));importScripts("/sw-lib.js");`,
+			want: []string{
+				"https://www.example.com/app/chunk.js",
+				"https://www.example.com/lib/a.js",
+				"https://www.example.com/app/w.js",
+				"https://www.example.com/sw-lib.js",
+			},
+		},
+		{
+			name: "unrequestable references",
+			body: `<a href="javascript:void(0)">x</a><a href="mailto:a@example.com">y</a><a href="#top">z</a><script src="${cdn}/a.js"></script>`,
+			want: nil,
+		},
+		{
+			name: "deduplicated",
+			body: `<script src="/a.js"></script><script src="https://www.example.com/a.js"></script>`,
+			want: []string{"https://www.example.com/a.js"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractLinks(tt.body, page)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExtractLinks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimURLPunct(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://a.com/x.js", "https://a.com/x.js"},
+		{"https://a.com/x.js)", "https://a.com/x.js"},
+		{"https://a.com/x.js).", "https://a.com/x.js"},
+		{"https://a.com/x.js],", "https://a.com/x.js"},
+		{"https://a.com/f(x)", "https://a.com/f(x)"},
+		{"https://a.com/f(x))", "https://a.com/f(x)"},
+		{"https://a.com/?q=1;", "https://a.com/?q=1"},
+	}
+	for _, tt := range tests {
+		if got := trimURLPunct(tt.in); got != tt.want {
+			t.Errorf("trimURLPunct(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
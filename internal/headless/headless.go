@@ -0,0 +1,38 @@
+package headless
+
+import (
+	"log"
+	"os"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+)
+
+// Launch 启动一个无头 Chrome 并返回已连接的 *rod.Browser，爬虫和登录流程共用同一套启动参数。
+// browserPath 为空时使用 Rod 默认的浏览器，proxy 不为空时为浏览器设置代理。
+func Launch(browserPath string, proxy string) *rod.Browser {
+	var chromePath string
+	if browserPath != "" {
+		chromePath = browserPath
+	} else {
+		// 首次运行时下载浏览器的进度输出到 stderr，避免混入 -o - 输出到 stdout 的 JSON Lines
+		b := launcher.NewBrowser()
+		b.Logger = log.New(os.Stderr, "[launcher.Browser]", log.LstdFlags)
+		chromePath = b.MustGet()
+	}
+
+	launch := launcher.New().
+		Bin(chromePath).
+		Headless(true).
+		Set("ignore-certificate-errors").
+		Set("disable-blink-features", "AutomationControlled").
+		Set("disable-infobars")
+
+	if proxy != "" {
+		launch = launch.Proxy(proxy)
+	}
+
+	u := launch.MustLaunch()
+
+	return rod.New().ControlURL(u).MustConnect()
+}
@@ -0,0 +1,303 @@
+package local
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"SecureJS/internal/matcher"
+	"SecureJS/internal/parser"
+)
+
+// maxArchiveDepth 限制压缩包嵌套的层数（如 war 中的 jar）
+const maxArchiveDepth = 3
+
+// minStringLength 是从二进制文件中提取可打印字符串时的最小长度
+const minStringLength = 6
+
+// skipDirs 是遍历目录时跳过的目录名
+var skipDirs = map[string]bool{
+	".git": true, ".svn": true, ".hg": true,
+}
+
+// skipExtensions 是不扫描的文件后缀(小写)：图片、音视频、字体、文档和可执行文件
+var skipExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".ico": true, ".webp": true,
+	".tif": true, ".tiff": true, ".avif": true,
+	".mp3": true, ".mp4": true, ".wav": true, ".ogg": true, ".webm": true, ".avi": true, ".mov": true, ".flac": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".ppt": true, ".pptx": true,
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".o": true, ".a": true,
+	".7z": true, ".rar": true, ".bz2": true, ".xz": true,
+}
+
+// scanner 遍历本地文件和压缩包，把每个文件转换为 ParseResult 交给匹配阶段
+type scanner struct {
+	maxSize int64
+	out     chan<- *parser.ParseResult
+	files   int
+}
+
+// Run 扫描本地文件和目录（目录会递归遍历），不启动浏览器也不发起请求。
+// .zip/.jar/.war/.tar/.tar.gz/.tgz 压缩包中的文件会被逐个解压扫描，.map 文件会还原其中的原始源码；
+// 每个文件的 URL 为 file:// 路径，压缩包中的文件为 "压缩包路径!/文件路径"。
+// 超过 maxSize 的文件会被截断，匹配结果通过 emit 输出，emit 会被多个 goroutine 并发调用。
+func Run(paths []string, maxSize int64, m *matcher.Matcher, emit func(*matcher.MatchResult)) {
+	docs := make(chan *parser.ParseResult, runtime.NumCPU())
+	matched := make(chan struct{})
+	go func() {
+		m.MatchStream(docs, runtime.NumCPU(), emit)
+		close(matched)
+	}()
+
+	s := &scanner{maxSize: maxSize, out: docs}
+	for _, p := range paths {
+		s.walk(p)
+	}
+	close(docs)
+	<-matched
+
+	log.Printf("[*] Scanned %d local file(s)", s.files)
+}
+
+// walk 递归遍历 root，root 也可以是单个文件
+func (s *scanner) walk(root string) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		s.fail(fileURL(root), err)
+		return
+	}
+	err = filepath.WalkDir(abs, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			s.fail(fileURL(p), err)
+			return nil
+		}
+		if d.IsDir() {
+			if p != abs && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			s.scanFile(p)
+		}
+		return nil
+	})
+	if err != nil {
+		s.fail(fileURL(abs), err)
+	}
+}
+
+// scanFile 扫描单个本地文件，压缩包直接从磁盘读取，不整体载入内存
+func (s *scanner) scanFile(p string) {
+	u := fileURL(p)
+	switch archiveKind(p) {
+	case "zip":
+		zr, err := zip.OpenReader(p)
+		if err != nil {
+			s.fail(u, err)
+			return
+		}
+		defer zr.Close()
+		s.scanZip(&zr.Reader, u, 1)
+	case "tar", "tgz":
+		f, err := os.Open(p)
+		if err != nil {
+			s.fail(u, err)
+			return
+		}
+		defer f.Close()
+		s.scanTar(f, archiveKind(p) == "tgz", u, 1)
+	default:
+		if skipExtensions[strings.ToLower(filepath.Ext(p))] {
+			return
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			s.fail(u, err)
+			return
+		}
+		defer f.Close()
+		s.scanReader(u, p, f, 0)
+	}
+}
+
+// scanZip 扫描 zip/jar/war 中的每个文件
+func (s *scanner) scanZip(zr *zip.Reader, base string, depth int) {
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		u := base + "!/" + f.Name
+		rc, err := f.Open()
+		if err != nil {
+			s.fail(u, err)
+			continue
+		}
+		s.scanReader(u, f.Name, rc, depth)
+		rc.Close()
+	}
+}
+
+// scanTar 扫描 tar 或 tar.gz 中的每个普通文件
+func (s *scanner) scanTar(r io.Reader, gzipped bool, base string, depth int) {
+	if gzipped {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			s.fail(base, err)
+			return
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			s.fail(base, err)
+			return
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		s.scanReader(base+"!/"+hdr.Name, hdr.Name, tr, depth)
+	}
+}
+
+// scanReader 读取一个文件的内容并交给匹配阶段；文件本身是压缩包且未超过嵌套层数时继续解压扫描。
+// name 为文件名，用于判断类型。
+func (s *scanner) scanReader(u string, name string, r io.Reader, depth int) {
+	kind := archiveKind(name)
+	if kind == "" && skipExtensions[strings.ToLower(path.Ext(name))] {
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		s.fail(u, err)
+		return
+	}
+	truncated := int64(len(data)) > s.maxSize
+
+	if kind != "" {
+		if depth >= maxArchiveDepth {
+			return
+		}
+		if truncated {
+			log.Printf("[!] %s: nested archive exceeds %d bytes, skipped", u, s.maxSize)
+			return
+		}
+		if kind == "zip" {
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				s.fail(u, err)
+				return
+			}
+			s.scanZip(zr, u, depth+1)
+		} else {
+			s.scanTar(bytes.NewReader(data), kind == "tgz", u, depth+1)
+		}
+		return
+	}
+
+	if truncated {
+		data = data[:s.maxSize]
+		log.Printf("[!] %s: file exceeds %d bytes, truncated", u, s.maxSize)
+	}
+	// 二进制文件（如 jar 中的 .class）只保留其中的可打印字符串
+	if isBinary(data) {
+		data = printableStrings(data)
+	}
+
+	header := http.Header{}
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		// 只取媒体类型，字符集仍按 BOM 和 <meta charset> 检测
+		header.Set("Content-Type", strings.TrimSpace(strings.Split(ct, ";")[0]))
+	}
+	pr := parser.FromBytes(u, header, data, s.maxSize)
+	pr.Truncated = pr.Truncated || truncated
+	s.files++
+	s.out <- pr
+}
+
+// fail 把读取失败的文件作为错误结果交给匹配阶段，与请求失败的 URL 一样出现在输出中
+func (s *scanner) fail(u string, err error) {
+	s.out <- &parser.ParseResult{URL: u, Error: fmt.Errorf("failed to read %s: %w", u, err)}
+}
+
+// archiveKind 根据文件名判断压缩包类型：zip（含 jar/war/ear/apk）、tar、tgz，不是压缩包时返回空字符串
+func archiveKind(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
+		return "tgz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	}
+	switch path.Ext(lower) {
+	case ".zip", ".jar", ".war", ".ear", ".apk", ".aar":
+		return "zip"
+	}
+	return ""
+}
+
+// fileURL 将本地路径转换为 file:// URL
+func fileURL(p string) string {
+	p = filepath.ToSlash(p)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// isBinary 判断内容是否是二进制：开头 8000 字节中出现 NUL 字节，且不是 gzip / zstd 压缩的内容
+func isBinary(data []byte) bool {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) || bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+		return false
+	}
+	head := data
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	return bytes.IndexByte(head, 0) != -1
+}
+
+// printableStrings 提取二进制内容中长度不小于 minStringLength 的可打印 ASCII 字符串，每行一个
+func printableStrings(data []byte) []byte {
+	var out bytes.Buffer
+	start := -1
+	flush := func(end int) {
+		if start >= 0 && end-start >= minStringLength {
+			out.Write(data[start:end])
+			out.WriteByte('\n')
+		}
+		start = -1
+	}
+	for i, b := range data {
+		if b == '\t' || (b >= 0x20 && b < 0x7f) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(data))
+	return out.Bytes()
+}
@@ -0,0 +1,134 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"SecureJS/config"
+)
+
+// allowlist 是编译后的过滤条件，在创建 Matcher 时编译一次
+type allowlist struct {
+	values    []*regexp.Regexp
+	keys      []*regexp.Regexp
+	stopwords []string // 已转为小写
+	paths     []*regexp.Regexp
+	nonASCII  bool
+}
+
+// compileAllowlist 编译过滤条件，cfg 为 nil 时返回 nil（不过滤）
+func compileAllowlist(cfg *config.Allowlist) (*allowlist, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	a := &allowlist{nonASCII: cfg.NonASCII}
+	for _, v := range cfg.Values {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist value regex '%s': %w", v, err)
+		}
+		a.values = append(a.values, re)
+	}
+	for _, k := range cfg.Keys {
+		re, err := regexp.Compile(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist key regex '%s': %w", k, err)
+		}
+		a.keys = append(a.keys, re)
+	}
+	for _, w := range cfg.Stopwords {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			a.stopwords = append(a.stopwords, w)
+		}
+	}
+	for _, p := range cfg.Paths {
+		a.paths = append(a.paths, globToRegexp(p))
+	}
+	return a, nil
+}
+
+// globToRegexp 将 glob 转换为完整匹配的正则：* 匹配任意字符（包括 /），? 匹配单个字符
+func globToRegexp(glob string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// filtered 判断一条命中结果是否命中过滤条件，paths 为需要检查的 URL 或原始文件路径
+func (a *allowlist) filtered(item *MatchItem, paths ...string) bool {
+	if a == nil {
+		return false
+	}
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		for _, re := range a.paths {
+			if re.MatchString(p) {
+				return true
+			}
+		}
+	}
+
+	key, value, ok := splitKeyValue(item)
+	if !ok {
+		return false
+	}
+	for _, re := range a.keys {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	for _, re := range a.values {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	lowerValue := strings.ToLower(value)
+	for _, w := range a.stopwords {
+		if strings.Contains(lowerValue, w) {
+			return true
+		}
+	}
+	return a.nonASCII && hasNonASCII(value)
+}
+
+// splitKeyValue 按整段匹配中第一个 : 或 = 拆分出字段名和取值，去除首尾的引号和空格。
+// 规则指定了 secret_group 时取值使用提取出的 Secret；找不到分隔符时返回 false。
+func splitKeyValue(item *MatchItem) (key string, value string, ok bool) {
+	text := strings.TrimSpace(item.MatchedText)
+	splitPos := strings.IndexAny(text, ":=")
+	if splitPos < 0 {
+		return "", "", false
+	}
+
+	key = strings.Trim(text[:splitPos], `"' `)
+	value = strings.Trim(text[splitPos+1:], `"' `)
+	if item.Secret != "" && item.Secret != item.MatchedText {
+		value = item.Secret
+	}
+	return key, value, true
+}
+
+// hasNonASCII 判断字符串中是否包含非 ASCII 字符
+func hasNonASCII(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package matcher
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"SecureJS/config"
+)
+
+const (
+	// jsExpr 匹配一个变量引用：t、e.data.token、window["token"]、a?.b、getToken()
+	jsExpr = `[A-Za-z_$][\w$]*(?:\??\.[A-Za-z_$][\w$]*|\[[^\]]+\])*(?:\(\))?`
+	// jsChain 匹配至少带一次属性访问或调用的变量引用，base64 取值中不会出现 . [ ( 等字符
+	jsChain = `[A-Za-z_$][\w$]*(?:(?:\??\.[A-Za-z_$][\w$]*|\[[^\]]+\])+(?:\(\))?|\(\))`
+)
+
+// 看起来像代码引用而不是具体数值的取值，如 this.state.token、+t.access_token+、${TOKEN}、getToken()。
+// 所有模式都匹配整个取值，取值中只有一部分像代码时不会被丢弃
+var codeRefPatterns = []*regexp.Regexp{
+	// 字符串拼接：'...'+t.access_token+'...' 被截出来的部分。+ 只在两端都有、紧挨引号或空白、
+	// 或与属性访问链相连时才视为拼接，避免丢掉以 + 开头或结尾的 base64 取值
+	regexp.MustCompile(`^['"\x60]?\s*\+\s*` + jsExpr + `\s*\+\s*['"\x60]?$`),
+	regexp.MustCompile(`^(?:['"\x60]\s*\+\s*` + jsExpr + `|\+\s+` + jsExpr + `|\+\s*` + jsChain + `)$`),
+	regexp.MustCompile(`^(?:` + jsExpr + `\s*\+\s*['"\x60]|` + jsExpr + `\s+\+|` + jsChain + `\s*\+)$`),
+	// 属性访问链：this.state.token、e.data.access_token、window["token"]、a?.b、process.env.TOKEN，
+	// 第一段必须是常见的对象或压缩后的单字母变量，避免丢掉 a1b2.c3d4 这类带点的取值
+	regexp.MustCompile(`^(?:this|window|self|globalThis|document|localStorage|sessionStorage|process\.env|import\.meta\.env|[A-Za-z_$])(?:\??\.[A-Za-z_$][\w$]*|\[[^\]]+\])+(?:\(\))?$`),
+	// 函数调用：getToken()、localStorage.getItem("token")
+	regexp.MustCompile(`^[A-Za-z_$][\w$.]*\(.*\)$`),
+	// 模板占位符：${TOKEN}、{{ token }}、<%= token %>、%TOKEN%
+	regexp.MustCompile(`^(?:\$\{[^}]*\}|\{\{[^}]*\}\}|<%=?[^%]*%>|%[A-Za-z_]\w*%)$`),
+	// 逻辑和比较表达式：a&&b、e===t、!n||r.token
+	regexp.MustCompile(`^!?` + jsExpr + `\s*(?:&&|\|\||[!=]==?)\s*!?(?:` + jsExpr + `|"[^"]*"|'[^']*'|\d+)$`),
+	// 以 JS 关键字或箭头函数开头的代码片段：return t、function(){...}、void 0、new Date()、e=>e.token
+	regexp.MustCompile(`^(?:return\s|function\s*[\w$]*\s*\(|void 0$|new [A-Z][\w$]*\(|\(?[\w$,\s]*\)?\s*=>)`),
+}
+
+// 各字符集允许的字符，base64 同时兼容 base64url
+var charsetPatterns = map[string]*regexp.Regexp{
+	"base64": regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`),
+	"hex":    regexp.MustCompile(`^[0-9A-Fa-f]+$`),
+	"alnum":  regexp.MustCompile(`^[A-Za-z0-9]+$`),
+}
+
+// shannonEntropy 计算字符串每个字符的香农熵（bits）
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	var entropy float64
+	for _, c := range counts {
+		p := float64(c) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// looksLikeCode 判断取值是否只是代码中的变量引用、函数调用或模板占位符
+func looksLikeCode(value string) bool {
+	for _, re := range codeRefPatterns {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// passScoring 判断 secret 取值是否满足打分条件：checkCode 为 true 时看起来像代码引用的取值不满足，
+// 熵、长度和字符集只在规则配置了 scoring 时检查
+func passScoring(sc *config.Scoring, checkCode bool, value string) bool {
+	value = strings.TrimSpace(value)
+	if checkCode && looksLikeCode(value) {
+		return false
+	}
+	if sc == nil {
+		return true
+	}
+	if sc.MinLength > 0 && len(value) < sc.MinLength {
+		return false
+	}
+	if re, ok := charsetPatterns[sc.Charset]; ok && !re.MatchString(value) {
+		return false
+	}
+	return sc.MinEntropy <= 0 || shannonEntropy(value) >= sc.MinEntropy
+}
+
+// applyScoring 按规则的 scoring 配置处理一条命中结果，返回 false 表示丢弃。
+// 代码引用只对配置了 scoring 或 secret_group 的规则检查：未指定 secret_group 时 Secret 是整段匹配
+// （如 PEM 私钥、服务账号 JSON），不能按代码特征判断。未配置 scoring 的规则按默认的 drop 处理
+func applyScoring(sc *config.Scoring, hasSecretGroup bool, item *MatchItem) bool {
+	if passScoring(sc, sc != nil || hasSecretGroup, item.Secret) {
+		return true
+	}
+	if sc == nil || sc.Action != "downgrade" {
+		return false
+	}
+	item.Severity = downgradeSeverity(item.Severity)
+	item.Confidence = "low"
+	return true
+}
+
+// downgradeSeverity 将严重程度降低一级，info 保持不变
+func downgradeSeverity(severity string) string {
+	for i, s := range config.Severities {
+		if s == severity && i+1 < len(config.Severities) {
+			return config.Severities[i+1]
+		}
+	}
+	return severity
+}
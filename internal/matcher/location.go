@@ -0,0 +1,102 @@
+package matcher
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"SecureJS/config"
+)
+
+// lineIndex 记录一段文本中每一行的起始偏移，用于把字节偏移换算为行号和列号
+type lineIndex struct {
+	text   string
+	starts []int
+}
+
+// newLineIndex 为文本建立行索引，只在该文本有命中时才会调用
+func newLineIndex(text string) *lineIndex {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &lineIndex{text: text, starts: starts}
+}
+
+// lineOf 返回偏移所在的行（从 0 开始）
+func (li *lineIndex) lineOf(offset int) int {
+	return sort.Search(len(li.starts), func(i int) bool { return li.starts[i] > offset }) - 1
+}
+
+// lineBounds 返回第 line 行（从 0 开始）的起止偏移，不包含换行符
+func (li *lineIndex) lineBounds(line int) (int, int) {
+	start := li.starts[line]
+	end := len(li.text)
+	if line+1 < len(li.starts) {
+		end = li.starts[line+1] - 1
+	}
+	return start, end
+}
+
+// position 返回偏移对应的行号和列号（均从 1 开始，列号按字符计）
+func (li *lineIndex) position(offset int) (int, int) {
+	line := li.lineOf(offset)
+	start, _ := li.lineBounds(line)
+	return line + 1, utf8.RuneCountInString(li.text[start:offset]) + 1
+}
+
+// snippet 返回 [start, end) 这段命中前后的上下文：
+// 所在行是压缩代码时取前后各 window.Chars 个字符，否则取前后各 window.Lines 行
+func (li *lineIndex) snippet(start, end int, window config.ContextWindow) string {
+	firstLine := li.lineOf(start)
+	lastLine := firstLine
+	if end > start {
+		lastLine = li.lineOf(end - 1)
+	}
+	lineStart, _ := li.lineBounds(firstLine)
+	_, lineEnd := li.lineBounds(lastLine)
+
+	if window.MinifiedLine > 0 && lineEnd-lineStart > window.MinifiedLine {
+		from := backRunes(li.text, start, window.Chars)
+		if from < lineStart {
+			from = lineStart
+		}
+		to := forwardRunes(li.text, end, window.Chars)
+		if to > lineEnd {
+			to = lineEnd
+		}
+		return li.text[from:to]
+	}
+
+	firstLine -= window.Lines
+	if firstLine < 0 {
+		firstLine = 0
+	}
+	lastLine += window.Lines
+	if lastLine >= len(li.starts) {
+		lastLine = len(li.starts) - 1
+	}
+	from, _ := li.lineBounds(firstLine)
+	_, to := li.lineBounds(lastLine)
+	return strings.ReplaceAll(li.text[from:to], "\r", "")
+}
+
+// backRunes 从 offset 向前移动 n 个字符，返回新的偏移
+func backRunes(text string, offset int, n int) int {
+	for ; n > 0 && offset > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(text[:offset])
+		offset -= size
+	}
+	return offset
+}
+
+// forwardRunes 从 offset 向后移动 n 个字符，返回新的偏移
+func forwardRunes(text string, offset int, n int) int {
+	for ; n > 0 && offset < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
@@ -0,0 +1,62 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+
+	"SecureJS/config"
+)
+
+func TestLineIndexPosition(t *testing.T) {
+	text := "line1\nsecond 行\r\nthird\n"
+	li := newLineIndex(text)
+	tests := []struct {
+		name      string
+		offset    int
+		line, col int
+	}{
+		{"start", 0, 1, 1},
+		{"end of first line", 4, 1, 5},
+		{"newline", 5, 1, 6},
+		{"second line", 6, 2, 1},
+		{"after multibyte", strings.Index(text, "\r"), 2, 9},
+		{"third line", strings.Index(text, "third"), 3, 1},
+		{"trailing empty line", len(text), 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, col := li.position(tt.offset)
+			if line != tt.line || col != tt.col {
+				t.Errorf("position(%d) = %d:%d, want %d:%d", tt.offset, line, col, tt.line, tt.col)
+			}
+		})
+	}
+}
+
+func TestLineIndexSnippet(t *testing.T) {
+	lines := "a\nb\r\nkey=SECRET\nc\nd"
+	minified := strings.Repeat("x", 30) + "key=SECRET" + strings.Repeat("y", 30)
+	tests := []struct {
+		name   string
+		text   string
+		match  string
+		window config.ContextWindow
+		want   string
+	}{
+		{"one line around", lines, "SECRET", config.ContextWindow{Lines: 1, Chars: 5, MinifiedLine: 500}, "b\nkey=SECRET\nc"},
+		{"lines clamped", lines, "SECRET", config.ContextWindow{Lines: 5, Chars: 5, MinifiedLine: 500}, "a\nb\nkey=SECRET\nc\nd"},
+		{"only matched line", lines, "SECRET", config.ContextWindow{Lines: 0, Chars: 5, MinifiedLine: 500}, "key=SECRET"},
+		{"minified chars", minified, "SECRET", config.ContextWindow{Lines: 2, Chars: 4, MinifiedLine: 20}, "key=SECRETyyyy"},
+		{"minified clamped", "key=SECRET" + strings.Repeat("y", 30), "SECRET", config.ContextWindow{Lines: 2, Chars: 10, MinifiedLine: 20}, "key=SECRETyyyyyyyyyy"},
+		{"minified multibyte", strings.Repeat("中", 30) + "SECRET" + strings.Repeat("文", 30), "SECRET", config.ContextWindow{Chars: 2, MinifiedLine: 20}, "中中SECRET文文"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			li := newLineIndex(tt.text)
+			start := strings.Index(tt.text, tt.match)
+			if got := li.snippet(start, start+len(tt.match), tt.window); got != tt.want {
+				t.Errorf("snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
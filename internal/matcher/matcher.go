@@ -3,16 +3,42 @@ package matcher
 import (
 	"fmt"
 	"regexp"
-	"strings"
+	"sort"
+	"strconv"
+	"sync"
 
 	"SecureJS/config"
 	"SecureJS/internal/parser"
+	"SecureJS/internal/scope"
 )
 
 // MatchItem 表示单条命中结果
 type MatchItem struct {
-	RuleName    string // 命中的规则名称
-	MatchedText string // 实际匹配到的敏感信息片段
+	RuleName    string   // 命中的规则名称
+	MatchedText string   // 实际匹配到的敏感信息片段（整段匹配，可能包含字段名和引号）
+	Secret      string   // 规则 secret_group 指定的捕获组，即真正的敏感值；未指定时与 MatchedText 相同
+	Severity    string   // 规则的严重程度
+	Confidence  string   // 规则的置信度
+	Tags        []string // 规则的标签
+	Description string   // 规则说明
+	Remediation string   // 修复建议
+	Entropy     float64  // Secret 的香农熵（bits/字符）
+	Offset      int      // 命中在响应内容（或 source map 原始源码）中的字节偏移
+	Line        int      // 命中所在行，从 1 开始
+	Column      int      // 命中所在列，从 1 开始，按字符计
+	Context     string   // 命中前后的上下文，范围由 config.yaml 的 context 段决定
+	Target      string   // 命中所在的匹配目标：body、headers 或 url
+	Location    string   // 命中位置，来自 source map 原始源码时为 "原始文件路径:行号:列号"，如 webpack:///src/config/aws.ts:12:7；来自响应头时为 "header:响应头名称"，来自 URL 时为 "url"
+	AI          *Verdict // AI 分析给出的判断，未启用 AI 分析或没有得到有效判断时为 nil
+}
+
+// Verdict 是 AI 分析对单条命中给出的判断
+type Verdict struct {
+	TruePositive bool    // 是否为真实的敏感信息泄露
+	Confidence   float64 // 判断的置信度，0~1
+	SecretType   string  // 敏感信息的类型，如 AWS Access Key、数据库密码
+	Reasoning    string  // 判断理由
+	Environment  string  // 推测的所属环境：production、staging、development、test 或 unknown
 }
 
 // MatchResult 表示对某个 URL 的匹配结果
@@ -24,15 +50,30 @@ type MatchResult struct {
 
 // compiledRule 用于保存编译后的正则，避免重复编译
 type compiledRule struct {
-	Name  string
-	Regex *regexp.Regexp
+	config.Rule
+	Regex        *regexp.Regexp
+	secretGroups []int      // secret_group 对应的捕获组序号（同名捕获组可能有多个，取第一个参与匹配的）
+	allowlist    *allowlist // 仅对该规则生效的过滤条件
+}
+
+// Matcher 保存编译后的规则和过滤条件，可以逐个匹配响应内容，用于边请求边输出结果
+type Matcher struct {
+	rules  map[string][]compiledRule // 按匹配目标（body / headers / url）分组的规则，target 为 all 的规则出现在每一组中
+	global *allowlist
+	window config.ContextWindow
+	sc     *scope.Scope
 }
 
-// MatchAll 对从 parser 获得的一组响应内容进行匹配，
-// 返回每个 URL 对应的匹配情况。
-func MatchAll(rules []config.Rule, parseResults []*parser.ParseResult) ([]*MatchResult, error) {
-	// 1) 先编译所有规则（减少重复编译）
-	compiledRules := make([]compiledRule, 0, len(rules))
+// New 编译所有规则和过滤条件。不在 sc 范围内的 URL 不会被报告，命中全局或规则 allowlist 的结果会被过滤，
+// window 决定每条命中结果附带的上下文范围。
+func New(rules []config.Rule, allow *config.Allowlist, window config.ContextWindow, sc *scope.Scope) (*Matcher, error) {
+	global, err := compileAllowlist(allow)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先编译所有规则（减少重复编译），再按匹配目标分组
+	compiledRules := make(map[string][]compiledRule)
 	for _, r := range rules {
 		// 如果 f_regex 为空或无效，可以跳过
 		if r.FRegex == "" {
@@ -42,157 +83,211 @@ func MatchAll(rules []config.Rule, parseResults []*parser.ParseResult) ([]*Match
 		if err != nil {
 			return nil, fmt.Errorf("failed to compile regex for rule '%s': %w", r.Name, err)
 		}
-		compiledRules = append(compiledRules, compiledRule{
-			Name:  r.Name,
-			Regex: re,
-		})
-	}
-
-	// 2) 对每个 parseResult 的 Body 做匹配
-	results := make([]*MatchResult, 0, len(parseResults))
-	for _, pr := range parseResults {
-		if pr.Error != nil {
-			// 如果 parse 出错了，这里就直接记录错误
-			results = append(results, &MatchResult{
-				URL:   pr.URL,
-				Error: pr.Error,
-			})
-			continue
+		groups, err := resolveSecretGroup(re, r.SecretGroup)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret_group for rule '%s': %w", r.Name, err)
+		}
+		ruleAllowlist, err := compileAllowlist(r.Allowlist)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': %w", r.Name, err)
+		}
+		cr := compiledRule{
+			Rule:         r,
+			Regex:        re,
+			secretGroups: groups,
+			allowlist:    ruleAllowlist,
+		}
+		for _, target := range []string{targetBody, targetHeaders, targetURL} {
+			if r.Target == target || r.Target == "all" || (r.Target == "" && target == targetBody) {
+				compiledRules[target] = append(compiledRules[target], cr)
+			}
+		}
+	}
+
+	return &Matcher{
+		rules:  compiledRules,
+		global: global,
+		window: window,
+		sc:     sc,
+	}, nil
+}
+
+// Match 匹配单个响应的 URL、响应头和响应内容，每条规则只匹配其 target 指定的部分。
+// 只有 URL 没有响应（如浏览器发出的图片、统计请求）时 pr 中只需设置 URL。请求失败时返回只带 Error 的结果；
+// 不在扫描范围内或没有任何命中时返回 nil。Match 可以被多个 goroutine 并发调用。
+func (m *Matcher) Match(pr *parser.ParseResult) *MatchResult {
+	if !m.sc.Allow(pr.URL) {
+		return nil
+	}
+	if pr.Error != nil {
+		// 如果 parse 出错了，这里就直接记录错误
+		return &MatchResult{
+			URL:   pr.URL,
+			Error: pr.Error,
 		}
+	}
+
+	//去重
+	uniqueMatches := make(map[string]bool)
+	// 准备收集此 URL 下所有命中项
+	var matchedItems []MatchItem
 
-		//去重
-		uniqueMatches := make(map[string]bool)
-		// 准备收集此 URL 下所有命中项
-		var matchedItems []MatchItem
-		body := pr.Body
-
-		// 对所有规则匹配
-		for _, cr := range compiledRules {
-			allMatches := cr.Regex.FindAllString(body, -1)
-			for _, matchStr := range allMatches {
-				if _, exists := uniqueMatches[matchStr]; !exists {
-					uniqueMatches[matchStr] = true
-					matchedItems = append(matchedItems, MatchItem{
-						RuleName:    cr.Name,
-						MatchedText: matchStr,
-					})
+	// URL 中的查询参数
+	matchedItems = matchText(m.rules[targetURL], m.global, m.window, pr.URL, textSource{text: pr.URL, target: targetURL}, uniqueMatches, matchedItems)
+
+	// 响应头逐行匹配，每行为 "名称: 值"
+	names := make([]string, 0, len(pr.Header))
+	for name := range pr.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range pr.Header[name] {
+			src := textSource{text: name + ": " + value, target: targetHeaders, header: name}
+			matchedItems = matchText(m.rules[targetHeaders], m.global, m.window, pr.URL, src, uniqueMatches, matchedItems)
+		}
+	}
+
+	// 先匹配 source map 中还原出的原始源码，这样同一个敏感信息会优先报告原始文件位置
+	for _, src := range pr.Sources {
+		matchedItems = matchText(m.rules[targetBody], m.global, m.window, pr.URL, textSource{text: src.Content, target: targetBody, path: src.Path}, uniqueMatches, matchedItems)
+	}
+	// source map 本身只是一个大 JSON，已经按原始源码匹配过了，不再整体匹配
+	if len(pr.Sources) == 0 || !parser.IsSourceMap(pr.URL, pr.Body) {
+		matchedItems = matchText(m.rules[targetBody], m.global, m.window, pr.URL, textSource{text: pr.Body, target: targetBody}, uniqueMatches, matchedItems)
+	}
+
+	if len(matchedItems) == 0 {
+		return nil
+	}
+	return &MatchResult{
+		URL:   pr.URL,
+		Items: matchedItems,
+		Error: nil,
+	}
+}
+
+// MatchStream 用 workers 个 goroutine 匹配 docs 中的响应内容，直到 docs 被关闭且全部匹配完成后返回。
+// 有命中或请求失败的结果通过 emit 输出，emit 会被多个 goroutine 并发调用。
+func (m *Matcher) MatchStream(docs <-chan *parser.ParseResult, workers int, emit func(*MatchResult)) {
+	if workers <= 0 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pr := range docs {
+				if mr := m.Match(pr); mr != nil {
+					emit(mr)
 				}
 			}
+		}()
+	}
+	wg.Wait()
+}
+
+// 匹配目标，对应规则的 target
+const (
+	targetBody    = "body"
+	targetHeaders = "headers"
+	targetURL     = "url"
+)
+
+// textSource 是一段待匹配的文本及其来源
+type textSource struct {
+	text   string
+	target string // 文本所属的匹配目标
+	path   string // 文本来自 source map 中的原始源码时为原始文件路径
+	header string // 文本来自响应头时为响应头名称
+}
+
+// matchText 使用所有规则匹配一段文本，把未出现过的命中追加到 matchedItems。
+// 每条命中都会记录偏移、行列号和上下文；文本来自 source map 中的原始源码时，命中位置记录为 "原始文件路径:行号:列号"。
+// 命中全局 allowlist（global）或规则自身 allowlist 的结果会被丢弃，pageURL 和原始文件路径用于路径过滤。
+func matchText(compiledRules []compiledRule, global *allowlist, window config.ContextWindow, pageURL string, src textSource, uniqueMatches map[string]bool, matchedItems []MatchItem) []MatchItem {
+	text, sourcePath := src.text, src.path
+	var li *lineIndex // 有命中时才建立行索引
+	for _, cr := range compiledRules {
+		for _, loc := range cr.Regex.FindAllStringSubmatchIndex(text, -1) {
+			matchStr := text[loc[0]:loc[1]]
+			if _, exists := uniqueMatches[matchStr]; exists {
+				continue
+			}
+
+			item := MatchItem{
+				RuleName:    cr.Name,
+				MatchedText: matchStr,
+				Secret:      extractSecret(text, loc, cr.secretGroups),
+				Severity:    cr.Severity,
+				Confidence:  cr.Confidence,
+				Tags:        cr.Tags,
+				Description: cr.Description,
+				Remediation: cr.Remediation,
+				Target:      src.target,
+			}
+			item.Entropy = shannonEntropy(item.Secret)
+			// 代码引用、低熵等不满足规则 scoring 条件的取值，按规则配置丢弃或降级
+			if !applyScoring(cr.Scoring, len(cr.secretGroups) > 0, &item) {
+				continue
+			}
+			if global.filtered(&item, pageURL, sourcePath) || cr.allowlist.filtered(&item, pageURL, sourcePath) {
+				continue
+			}
+			uniqueMatches[matchStr] = true
+
+			if li == nil {
+				li = newLineIndex(text)
+			}
+			item.Offset = loc[0]
+			item.Line, item.Column = li.position(loc[0])
+			item.Context = li.snippet(loc[0], loc[1], window)
+			switch {
+			case sourcePath != "":
+				item.Location = fmt.Sprintf("%s:%d:%d", sourcePath, item.Line, item.Column)
+			case src.header != "":
+				item.Location = "header:" + src.header
+			case src.target == targetURL:
+				item.Location = targetURL
+			}
+			matchedItems = append(matchedItems, item)
+		}
+	}
+	return matchedItems
+}
+
+// resolveSecretGroup 将 secret_group（捕获组序号或名称）解析为捕获组序号列表，为空时返回 nil 表示取整段匹配
+func resolveSecretGroup(re *regexp.Regexp, group string) ([]int, error) {
+	if group == "" {
+		return nil, nil
+	}
+	if idx, err := strconv.Atoi(group); err == nil {
+		if idx < 0 || idx > re.NumSubexp() {
+			return nil, fmt.Errorf("group %d out of range, regex has %d capture group(s)", idx, re.NumSubexp())
 		}
-		// 过滤匹配项
-		matchedItems = filterMatchedItems(matchedItems)
-
-		// 如果有匹配项，记录结果
-		if len(matchedItems) > 0 {
-			results = append(results, &MatchResult{
-				URL:   pr.URL,
-				Items: matchedItems,
-				Error: nil,
-			})
+		return []int{idx}, nil
+	}
+
+	var groups []int
+	for i, name := range re.SubexpNames() {
+		if name == group {
+			groups = append(groups, i)
 		}
 	}
-	// 输出匹配结果
-	// var lastURL string
-	// for _, result := range results {
-	// 	for _, item := range result.Items {
-	// 		if item.MatchedText != "" {
-	// 			if lastURL != result.URL {
-	// 				if lastURL != "" {
-	// 					fmt.Println() // 为了分隔不同的URL输出
-	// 				}
-	// 				fmt.Printf("URL: %s\n", result.URL)
-	// 				lastURL = result.URL
-	// 			}
-	// 			fmt.Printf("  Rule: %s, Matched: %s\n", item.RuleName, item.MatchedText)
-	// 		}
-	// 	}
-	// }
-	return results, nil
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no capture group named '%s'", group)
+	}
+	return groups, nil
 }
 
-// filterMatchedItems 只会针对“找到的第一个 : 或 =”进行拆分。
-// 拆分后的 key/value 如果包含了指定的关键词或中文字符，就过滤掉。
-func filterMatchedItems(matchedItems []MatchItem) []MatchItem {
-    // 1) 设定普通过滤关键词（子串匹配、忽略大小写）
-    filterKeywords := []string{
-        "xml",
-    }
-
-    // 2) 前置过滤关键词（只要 key 包含这些，就过滤）
-    preFilterKeywords := []string{
-        "passive",
-    }
-
-    // 3) 编译对应的正则，用于子串匹配和忽略大小写
-    keywordRegex := regexp.MustCompile(`(?i)(` + strings.Join(filterKeywords, "|") + `)`)
-    preKeywordRegex := regexp.MustCompile(`(?i)(` + strings.Join(preFilterKeywords, "|") + `)`)
-
-    // 4) 检查是否有中文字符
-    chineseRegex := regexp.MustCompile(`[\p{Han}]`)
-
-    var filteredItems []MatchItem
-
-    for _, item := range matchedItems {
-        // 默认不过滤
-        shouldFilter := false
-
-        // 取出整条文本
-        text := strings.TrimSpace(item.MatchedText)
-
-        // 找到第一个 ':' 或 '='
-        idxColon := strings.IndexRune(text, ':')
-        idxEqual := strings.IndexRune(text, '=')
-
-        var splitPos int
-        switch {
-        case idxColon < 0 && idxEqual < 0:
-            // 连一个 ':' 或 '=' 都没有，说明无法拆分
-            // 你可以选择：直接保留 (shouldFilter=false)，也可以选择直接过滤
-            splitPos = -1
-        case idxColon < 0:
-            // 没有冒号，只找到等号
-            splitPos = idxEqual
-        case idxEqual < 0:
-            // 没有等号，只找到冒号
-            splitPos = idxColon
-        default:
-            // 同时存在 ':' 和 '='，取最小位置 => 谁先出现
-            if idxColon < idxEqual {
-                splitPos = idxColon
-            } else {
-                splitPos = idxEqual
-            }
-        }
-
-        if splitPos != -1 {
-            // 说明找到了分隔符，进行拆分
-            key := text[:splitPos]
-            value := text[splitPos+1:] // 从分隔符的下一个字符开始到末尾都算 value
-
-            // 转小写并去除首尾的引号、空格
-            key = strings.ToLower(strings.Trim(key, `"' `))
-            value = strings.ToLower(strings.Trim(value, `"' `))
-
-            // ① 如果 key 命中“前置过滤关键词”，则过滤
-            if preKeywordRegex.MatchString(key) {
-                shouldFilter = true
-            }
-
-            // ② value 中出现“普通过滤关键词”，则过滤
-            if keywordRegex.MatchString(value) {
-                shouldFilter = true
-            }
-
-            // ③ value 中包含中文字符，也过滤
-            if chineseRegex.MatchString(value) {
-                shouldFilter = true
-            }
-        }
-
-        if !shouldFilter {
-            filteredItems = append(filteredItems, item)
-        }
-    }
-
-    return filteredItems
+// extractSecret 从一次匹配的位置信息中取出第一个参与匹配的 secret 捕获组，都没有参与时返回整段匹配
+func extractSecret(text string, loc []int, groups []int) string {
+	for _, g := range groups {
+		start, end := loc[2*g], loc[2*g+1]
+		if start >= 0 && end > start {
+			return text[start:end]
+		}
+	}
+	return text[loc[0]:loc[1]]
 }
@@ -0,0 +1,162 @@
+package output
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"SecureJS/config"
+	"SecureJS/internal/matcher"
+)
+
+//go:embed report.html
+var reportTemplate string
+
+// htmlReport 是 HTML 报告模板使用的数据
+type htmlReport struct {
+	Generated  string
+	URLCount   int
+	Findings   int
+	AIAnalyzed int         // 得到 AI 判断的命中数，为 0 时报告中不显示 AI 相关的内容
+	AIPositive int         // 被 AI 判断为真实泄露的命中数
+	Severities []htmlCount // 按严重程度统计的命中数
+	Rules      []htmlCount // 按规则统计的命中数
+	Hosts      []htmlHost
+	Failed     []htmlFailure
+}
+
+type htmlCount struct {
+	Name     string
+	Severity string
+	Count    int
+}
+
+type htmlHost struct {
+	Host     string
+	Findings int
+	URLs     []htmlURL
+}
+
+type htmlURL struct {
+	URL      string
+	Findings int
+	Rules    []htmlRule
+}
+
+type htmlRule struct {
+	Name  string
+	Items []matcher.MatchItem
+}
+
+type htmlFailure struct {
+	URL   string
+	Error string
+}
+
+// writeHTML 输出单文件的 HTML 报告（CSS/JS 内嵌），命中结果按主机、URL、规则分组，
+// 包含统计面板、按严重程度的标记、AI 判断、可折叠的上下文、搜索过滤以及请求失败的 URL 列表
+func writeHTML(results []*matcher.MatchResult, rules []config.Rule, w io.Writer) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"upper": strings.ToUpper,
+		"join":  strings.Join,
+		"percent": func(v float64) string {
+			return fmt.Sprintf("%.0f%%", v*100)
+		},
+	}).Parse(reportTemplate)
+	if err != nil {
+		return fmt.Errorf("html template error: %w", err)
+	}
+
+	report := buildHTMLReport(results, rules)
+	if err := tmpl.Execute(w, report); err != nil {
+		return fmt.Errorf("html render error: %w", err)
+	}
+	return nil
+}
+
+// buildHTMLReport 将匹配结果整理为按主机、URL、规则分组的报告数据
+func buildHTMLReport(results []*matcher.MatchResult, rules []config.Rule) htmlReport {
+	report := htmlReport{
+		Generated: time.Now().Format("2006-01-02 15:04:05"),
+		URLCount:  len(results),
+	}
+
+	// 规则按配置文件中的顺序统计，没有命中的规则不显示
+	ruleCounts := make(map[string]int)
+	ruleSeverity := make(map[string]string)
+	severityCounts := make(map[string]int)
+	hostIndex := make(map[string]int)
+
+	for _, mr := range results {
+		if mr.Error != nil {
+			report.Failed = append(report.Failed, htmlFailure{URL: mr.URL, Error: mr.Error.Error()})
+			continue
+		}
+		if len(mr.Items) == 0 {
+			continue
+		}
+
+		host := mr.URL
+		if u, err := url.Parse(mr.URL); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		idx, ok := hostIndex[host]
+		if !ok {
+			idx = len(report.Hosts)
+			hostIndex[host] = idx
+			report.Hosts = append(report.Hosts, htmlHost{Host: host})
+		}
+
+		hu := htmlURL{URL: mr.URL, Findings: len(mr.Items)}
+		ruleIdx := make(map[string]int)
+		for _, item := range mr.Items {
+			i, ok := ruleIdx[item.RuleName]
+			if !ok {
+				i = len(hu.Rules)
+				ruleIdx[item.RuleName] = i
+				hu.Rules = append(hu.Rules, htmlRule{Name: item.RuleName})
+			}
+			hu.Rules[i].Items = append(hu.Rules[i].Items, item)
+
+			ruleCounts[item.RuleName]++
+			if item.AI != nil {
+				report.AIAnalyzed++
+				if item.AI.TruePositive {
+					report.AIPositive++
+				}
+			}
+			severityCounts[item.Severity]++
+			if _, ok := ruleSeverity[item.RuleName]; !ok {
+				ruleSeverity[item.RuleName] = item.Severity
+			}
+		}
+
+		report.Hosts[idx].URLs = append(report.Hosts[idx].URLs, hu)
+		report.Hosts[idx].Findings += len(mr.Items)
+		report.Findings += len(mr.Items)
+	}
+
+	sort.Slice(report.Hosts, func(i, j int) bool { return report.Hosts[i].Host < report.Hosts[j].Host })
+
+	for _, s := range config.Severities {
+		report.Severities = append(report.Severities, htmlCount{Name: s, Severity: s, Count: severityCounts[s]})
+	}
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if seen[r.Name] || ruleCounts[r.Name] == 0 {
+			continue
+		}
+		seen[r.Name] = true
+		severity := r.Severity
+		if severity == "" {
+			severity = ruleSeverity[r.Name]
+		}
+		report.Rules = append(report.Rules, htmlCount{Name: r.Name, Severity: severity, Count: ruleCounts[r.Name]})
+	}
+	return report
+}
@@ -0,0 +1,133 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"SecureJS/internal/matcher"
+)
+
+// jsonlRecord 是 JSON Lines 输出中每一行的结构。
+// type 为 finding 时表示一条命中，为 error 时表示一个请求失败的 URL；
+// url、rule、secret、severity、offset、timestamp 字段始终存在。
+type jsonlRecord struct {
+	Type       string        `json:"type"`
+	URL        string        `json:"url"`
+	Rule       string        `json:"rule"`
+	Secret     string        `json:"secret"`
+	Severity   string        `json:"severity"`
+	Offset     int           `json:"offset"`
+	Timestamp  string        `json:"timestamp"`
+	Confidence string        `json:"confidence,omitempty"`
+	Tags       []string      `json:"tags,omitempty"`
+	Matched    string        `json:"matched,omitempty"`
+	Target     string        `json:"target,omitempty"`
+	Line       int           `json:"line,omitempty"`
+	Column     int           `json:"column,omitempty"`
+	Location   string        `json:"location,omitempty"`
+	Context    string        `json:"context,omitempty"`
+	AI         *jsonlVerdict `json:"ai,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// jsonlVerdict 是 AI 分析给出的判断，字段与要求大模型输出的 JSON 一致
+type jsonlVerdict struct {
+	TruePositive bool    `json:"true_positive"`
+	Confidence   float64 `json:"confidence"`
+	SecretType   string  `json:"secret_type,omitempty"`
+	Reasoning    string  `json:"reasoning"`
+	Environment  string  `json:"environment"`
+}
+
+// JSONLWriter 以 JSON Lines 格式逐条输出结果，每条命中、每个请求失败的 URL 各占一行，
+// 写入后立即可见，便于 `SecureJS ... -o - | jq` 在扫描过程中处理结果。可以被多个 goroutine 并发调用。
+type JSONLWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLWriter 创建写入 w 的 JSONLWriter
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &JSONLWriter{enc: enc}
+}
+
+// Write 输出一个 URL 的匹配结果，mr 为 nil 时忽略
+func (jw *JSONLWriter) Write(mr *matcher.MatchResult) error {
+	if mr == nil {
+		return nil
+	}
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+
+	if mr.Error != nil {
+		return jw.encode(jsonlRecord{
+			Type:      "error",
+			URL:       mr.URL,
+			Timestamp: now,
+			Error:     mr.Error.Error(),
+		})
+	}
+	for _, item := range mr.Items {
+		err := jw.encode(jsonlRecord{
+			Type:       "finding",
+			URL:        mr.URL,
+			Rule:       item.RuleName,
+			Secret:     item.Secret,
+			Severity:   item.Severity,
+			Offset:     item.Offset,
+			Timestamp:  now,
+			Confidence: item.Confidence,
+			Tags:       item.Tags,
+			Matched:    item.MatchedText,
+			Target:     item.Target,
+			Line:       item.Line,
+			Column:     item.Column,
+			Location:   item.Location,
+			Context:    item.Context,
+			AI:         newJSONLVerdict(item.AI),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newJSONLVerdict 转换 AI 判断，没有判断时返回 nil
+func newJSONLVerdict(v *matcher.Verdict) *jsonlVerdict {
+	if v == nil {
+		return nil
+	}
+	return &jsonlVerdict{
+		TruePositive: v.TruePositive,
+		Confidence:   v.Confidence,
+		SecretType:   v.SecretType,
+		Reasoning:    v.Reasoning,
+		Environment:  v.Environment,
+	}
+}
+
+func (jw *JSONLWriter) encode(rec jsonlRecord) error {
+	if err := jw.enc.Encode(rec); err != nil {
+		return fmt.Errorf("jsonl write error: %w", err)
+	}
+	return nil
+}
+
+// writeJSONL 以 JSON Lines 格式一次性写出所有结果
+func writeJSONL(results []*matcher.MatchResult, w io.Writer) error {
+	jw := NewJSONLWriter(w)
+	for _, mr := range results {
+		if err := jw.Write(mr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
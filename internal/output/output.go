@@ -7,8 +7,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"SecureJS/config"
 	"SecureJS/internal/matcher"
 )
 
@@ -26,14 +28,20 @@ func PrintResultsToConsole(results []*matcher.MatchResult) {
 		}
 		fmt.Printf("\n[+] %s: found %d item(s)\n", mr.URL, len(mr.Items))
 		for _, item := range mr.Items {
-			fmt.Printf("    - Rule: %s, Matched: %s\n", item.RuleName, item.MatchedText)
+			fmt.Printf("    - %s\n", formatItem(item))
+			fmt.Print(formatVerdict(item, "      "))
+			fmt.Print(formatContext(item, "      "))
 		}
 	}
 }
 
 // WriteResultsToFile 将匹配结果写入指定文件；如果没有敏感信息则跳过该URL，不写入。
-// ext 可以是 ".txt" / ".csv" / ".json"，否则视为 ".txt"。
-func WriteResultsToFile(results []*matcher.MatchResult, outPath string) error {
+// ext 可以是 ".txt" / ".csv" / ".json" / ".jsonl" / ".sarif" / ".html"，否则视为 ".txt"；rules 用于生成 SARIF 的规则描述和 HTML 报告的规则统计。
+// outPath 为 - 时以 JSON Lines 格式输出到标准输出。
+func WriteResultsToFile(results []*matcher.MatchResult, rules []config.Rule, outPath string) error {
+	if outPath == "-" {
+		return writeJSONL(results, os.Stdout)
+	}
 	ext := strings.ToLower(filepath.Ext(outPath))
 	if ext == "" {
 		ext = ".txt"
@@ -52,12 +60,35 @@ func WriteResultsToFile(results []*matcher.MatchResult, outPath string) error {
 		return writeCSV(results, f)
 	case ".json":
 		return writeJSON(results, f)
+	case ".jsonl":
+		return writeJSONL(results, f)
+	case ".sarif":
+		return writeSARIF(results, rules, f)
+	case ".html", ".htm":
+		return writeHTML(results, rules, f)
 	default:
-		// 如果后缀不是这三个，默认按 txt 处理
+		// 如果后缀不是以上几种，默认按 txt 处理
 		return writeTxt(results, f)
 	}
 }
 
+// IsStream 判断 -o 参数是否要求流式输出：- 表示输出到标准输出，.jsonl 表示写入 JSON Lines 文件
+func IsStream(outPath string) bool {
+	return outPath == "-" || strings.ToLower(filepath.Ext(outPath)) == ".jsonl"
+}
+
+// NewStream 打开流式输出，返回逐条写入结果的 JSONLWriter 和关闭输出的函数
+func NewStream(outPath string) (*JSONLWriter, func() error, error) {
+	if outPath == "-" {
+		return NewJSONLWriter(os.Stdout), func() error { return nil }, nil
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create file error: %w", err)
+	}
+	return NewJSONLWriter(f), f.Close, nil
+}
+
 // writeTxt：只写有敏感信息的记录
 func writeTxt(results []*matcher.MatchResult, w io.Writer) error {
 	for _, mr := range results {
@@ -73,7 +104,15 @@ func writeTxt(results []*matcher.MatchResult, w io.Writer) error {
 		// 写有敏感信息的
 		_, _ = fmt.Fprintf(w, "[+] %s: found %d item(s)\n", mr.URL, len(mr.Items))
 		for _, item := range mr.Items {
-			_, _ = fmt.Fprintf(w, "    - Rule: %s, Matched: %s\n", item.RuleName, item.MatchedText)
+			_, _ = fmt.Fprintf(w, "    - %s\n", formatItem(item))
+			_, _ = fmt.Fprint(w, formatVerdict(item, "      "))
+			_, _ = fmt.Fprint(w, formatContext(item, "      "))
+			if item.Description != "" {
+				_, _ = fmt.Fprintf(w, "      Description: %s\n", item.Description)
+			}
+			if item.Remediation != "" {
+				_, _ = fmt.Fprintf(w, "      Remediation: %s\n", item.Remediation)
+			}
 		}
 		_, _ = fmt.Fprintln(w) // 空行分隔
 	}
@@ -86,21 +125,31 @@ func writeCSV(results []*matcher.MatchResult, w io.Writer) error {
 	defer csvWriter.Flush()
 
 	// 写表头
-	_ = csvWriter.Write([]string{"URL", "Rule", "MatchedText", "Error"})
+	_ = csvWriter.Write([]string{"URL", "Rule", "Severity", "Confidence", "Tags", "Secret", "MatchedText", "Location", "Offset", "Line", "Column", "Context", "Description", "Remediation", "Error",
+		"AIVerdict", "AIConfidence", "AISecretType", "AIEnvironment", "AIReasoning"})
 
 	for _, mr := range results {
 		if mr.Error != nil {
 			// 如果整个页面解析出错，也写一行记录
-			_ = csvWriter.Write([]string{mr.URL, "", "", mr.Error.Error()})
+			_ = csvWriter.Write([]string{mr.URL, "", "", "", "", "", "", "", "", "", "", "", "", "", mr.Error.Error(), "", "", "", "", ""})
 			continue
 		}
 		// 如果没报错但也没有任何命中 -> 跳过
 		if len(mr.Items) == 0 {
 			continue
 		}
-		// 写出匹配的条目
+		// 写出匹配的条目，没有 AI 判断时 AI 相关的列为空
 		for _, item := range mr.Items {
-			_ = csvWriter.Write([]string{mr.URL, item.RuleName, item.MatchedText, ""})
+			aiColumns := []string{"", "", "", "", ""}
+			if v := item.AI; v != nil {
+				aiColumns = []string{verdictLabel(v), strconv.FormatFloat(v.Confidence, 'f', 2, 64), v.SecretType, v.Environment, v.Reasoning}
+			}
+			_ = csvWriter.Write(append([]string{
+				mr.URL, item.RuleName, item.Severity, item.Confidence, strings.Join(item.Tags, ";"),
+				item.Secret, item.MatchedText, item.Location,
+				strconv.Itoa(item.Offset), strconv.Itoa(item.Line), strconv.Itoa(item.Column), item.Context,
+				item.Description, item.Remediation, "",
+			}, aiColumns...))
 		}
 	}
 	return nil
@@ -129,3 +178,61 @@ func writeJSON(results []*matcher.MatchResult, w io.Writer) error {
 	_, _ = w.Write(data)
 	return nil
 }
+
+// formatItem 返回单条命中结果的展示文本，如：
+// [HIGH] Rule: Azure Key (confidence: medium, tags: cloud,azure), Secret: xxx, Matched: AZURE_STORAGE_KEY="xxx", Line: 1, Column: 1204, Offset: 1203
+// Secret 与整段匹配相同时只显示 Matched；命中来自 source map 原始源码、响应头或 URL 时显示 Location。
+func formatItem(item matcher.MatchItem) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] Rule: %s (confidence: %s", strings.ToUpper(item.Severity), item.RuleName, item.Confidence)
+	if len(item.Tags) > 0 {
+		fmt.Fprintf(&sb, ", tags: %s", strings.Join(item.Tags, ","))
+	}
+	sb.WriteString(")")
+	if item.Secret != "" && item.Secret != item.MatchedText {
+		fmt.Fprintf(&sb, ", Secret: %s", item.Secret)
+	}
+	fmt.Fprintf(&sb, ", Matched: %s", item.MatchedText)
+	if item.Location != "" {
+		fmt.Fprintf(&sb, ", Location: %s", item.Location)
+	} else if item.Line > 0 {
+		fmt.Fprintf(&sb, ", Line: %d, Column: %d, Offset: %d", item.Line, item.Column, item.Offset)
+	}
+	return sb.String()
+}
+
+// formatContext 返回命中上下文的展示文本，每行加上 indent 缩进；没有上下文时返回空字符串
+func formatContext(item matcher.MatchItem, indent string) string {
+	if item.Context == "" {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(indent + "Context:\n")
+	for _, line := range strings.Split(item.Context, "\n") {
+		sb.WriteString(indent + "  | " + line + "\n")
+	}
+	return sb.String()
+}
+
+// formatVerdict 返回 AI 判断的展示文本，如：
+// AI: true positive (confidence: 0.92, environment: production, type: AWS Access Key) - 硬编码的生产环境密钥
+// 没有 AI 判断时返回空字符串
+func formatVerdict(item matcher.MatchItem, indent string) string {
+	v := item.AI
+	if v == nil {
+		return ""
+	}
+	text := fmt.Sprintf("%sAI: %s (confidence: %.2f, environment: %s", indent, strings.ReplaceAll(verdictLabel(v), "_", " "), v.Confidence, v.Environment)
+	if v.SecretType != "" {
+		text += ", type: " + v.SecretType
+	}
+	return text + ") - " + v.Reasoning + "\n"
+}
+
+// verdictLabel 返回 AI 判断的结论：true_positive 或 false_positive
+func verdictLabel(v *matcher.Verdict) string {
+	if v.TruePositive {
+		return "true_positive"
+	}
+	return "false_positive"
+}
@@ -0,0 +1,388 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"SecureJS/config"
+	"SecureJS/internal/matcher"
+)
+
+// SARIF 2.1.0 中用到的结构，只包含本工具需要的字段
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string                     `json:"name"`
+	Rules []sarifReportingDescriptor `json:"rules"`
+}
+
+type sarifReportingDescriptor struct {
+	ID                   string              `json:"id"`
+	Name                 string              `json:"name"`
+	ShortDescription     sarifMessage        `json:"shortDescription"`
+	FullDescription      *sarifMessage       `json:"fullDescription,omitempty"`
+	Help                 *sarifMessage       `json:"help,omitempty"`
+	DefaultConfiguration sarifRuleConfig     `json:"defaultConfiguration"`
+	Properties           sarifRuleProperties `json:"properties"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifRuleProperties struct {
+	Tags             []string `json:"tags,omitempty"`
+	Precision        string   `json:"precision"`
+	SecuritySeverity string   `json:"security-severity"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID           string             `json:"ruleId"`
+	RuleIndex        int                `json:"ruleIndex"`
+	Level            string             `json:"level"`
+	Message          sarifMessage       `json:"message"`
+	Locations        []sarifLocation    `json:"locations"`
+	RelatedLocations []sarifLocation    `json:"relatedLocations,omitempty"`
+	Fingerprints     map[string]string  `json:"fingerprints"`
+	Suppressions     []sarifSuppression `json:"suppressions,omitempty"`
+	Properties       map[string]any     `json:"properties,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Status        string `json:"status"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               *int                  `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+	ContextRegion    *sarifRegion          `json:"contextRegion,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine,omitempty"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	ByteOffset  *int          `json:"byteOffset,omitempty"`
+	ByteLength  int           `json:"byteLength,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+// sarifFingerprintKey 是结果指纹的名称，算法变化时需要升级版本号
+const sarifFingerprintKey = "secureJsFinding/v2"
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sarifRuleID 将规则名称转换为稳定的规则 ID，如 "Extended Cloud Key" => "extended-cloud-key"
+func sarifRuleID(name string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// sarifLevel 将严重程度映射为 SARIF 的 level
+func sarifLevel(severity string) string {
+	switch severity {
+	case "critical", "high":
+		return "error"
+	case "medium":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// sarifSecuritySeverity 将严重程度映射为代码扫描平台使用的 security-severity 分值
+func sarifSecuritySeverity(severity string) string {
+	switch severity {
+	case "critical":
+		return "9.5"
+	case "high":
+		return "8.0"
+	case "medium":
+		return "5.5"
+	case "low":
+		return "3.0"
+	default:
+		return "0.0"
+	}
+}
+
+// sarifFingerprint 根据规则、命中所在的文件和 secret 计算稳定的指纹，不包含偏移和行号。
+// 命中来自 source map 时文件为主机加原始文件路径，否则为去掉查询参数和文件名中内容哈希的 URL，
+// 这样重新构建后文件名变化（如 main.3f9a1c.js => main.8b2e4d.js）的 bundle 中仍能去重
+func sarifFingerprint(ruleID string, rawURL string, item matcher.MatchItem) string {
+	file := fingerprintURL(rawURL)
+	if sp := sourcePath(item); sp != "" {
+		file = fingerprintHost(rawURL) + "\x00" + sp
+	}
+	sum := sha256.Sum256([]byte(ruleID + "\x00" + file + "\x00" + item.Secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprintHost 返回 URL 中小写的主机，解析失败时原样返回
+func fingerprintHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(u.Host)
+}
+
+// fingerprintURL 去掉 URL 的查询参数、锚点和文件名中的内容哈希，解析失败时原样返回
+func fingerprintURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	dir, file := path.Split(u.Path)
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + dir + stripContentHash(file)
+}
+
+var (
+	// 内容哈希：6 位以上的十六进制串，或 8 位以上同时包含字母和数字的串，如 main.3f9a1c.js、index-B3kx9Qz2.js
+	hexHashRegex   = regexp.MustCompile(`^[0-9a-f]{6,}$`)
+	alnumHashRegex = regexp.MustCompile(`^[A-Za-z0-9_]{8,}$`)
+	hasDigitRegex  = regexp.MustCompile(`[0-9]`)
+	hasLetterRegex = regexp.MustCompile(`[A-Za-z]`)
+)
+
+// stripContentHash 去掉文件名中以 . 或 - 分隔的内容哈希，文件名的第一段和扩展名保持不变，
+// 如 main.3f9a1c.js => main.js、123.a1b2c3d4.chunk.js => 123.chunk.js、index-B3kx9Qz2.js => index.js
+func stripContentHash(file string) string {
+	parts := strings.Split(file, ".")
+	if len(parts) < 2 {
+		return file
+	}
+	kept := []string{trimHashSuffix(parts[0])}
+	for _, p := range parts[1 : len(parts)-1] {
+		if !isContentHash(p) {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(append(kept, parts[len(parts)-1]), ".")
+}
+
+// trimHashSuffix 去掉文件名第一段中 - 之后的内容哈希，如 index-B3kx9Qz2 => index
+func trimHashSuffix(name string) string {
+	if idx := strings.LastIndex(name, "-"); idx > 0 && isContentHash(name[idx+1:]) {
+		return name[:idx]
+	}
+	return name
+}
+
+// isContentHash 判断文件名中的一段是否像构建工具生成的内容哈希
+func isContentHash(s string) bool {
+	if !hasDigitRegex.MatchString(s) {
+		return false
+	}
+	return hexHashRegex.MatchString(s) || (alnumHashRegex.MatchString(s) && hasLetterRegex.MatchString(s))
+}
+
+// sourcePath 返回来自 source map 的命中所在的原始文件路径，Location 形如 "path:行号:列号"；
+// 命中来自响应头或 URL 时返回空
+func sourcePath(item matcher.MatchItem) string {
+	if !inBody(item) {
+		return ""
+	}
+	path := item.Location
+	for i := 0; i < 2; i++ {
+		if idx := strings.LastIndex(path, ":"); idx > 0 {
+			path = path[:idx]
+		}
+	}
+	return path
+}
+
+// writeSARIF 按 SARIF 2.1.0 输出结果：每条规则对应一个 reportingDescriptor，每条命中对应一个 result，
+// 请求失败的 URL 记录为 toolExecutionNotifications
+func writeSARIF(results []*matcher.MatchResult, rules []config.Rule, w io.Writer) error {
+	driver := sarifDriver{Name: "SecureJS"}
+	ruleIndex := make(map[string]int, len(rules))
+	for _, r := range rules {
+		if _, exists := ruleIndex[r.Name]; exists {
+			continue
+		}
+		ruleIndex[r.Name] = len(driver.Rules)
+
+		rd := sarifReportingDescriptor{
+			ID:                   sarifRuleID(r.Name),
+			Name:                 r.Name,
+			ShortDescription:     sarifMessage{Text: r.Name},
+			DefaultConfiguration: sarifRuleConfig{Level: sarifLevel(r.Severity)},
+			Properties: sarifRuleProperties{
+				Tags:             append([]string{"security"}, r.Tags...),
+				Precision:        r.Confidence,
+				SecuritySeverity: sarifSecuritySeverity(r.Severity),
+			},
+		}
+		if r.Description != "" {
+			rd.FullDescription = &sarifMessage{Text: r.Description}
+		}
+		if r.Remediation != "" {
+			rd.Help = &sarifMessage{Text: r.Remediation}
+		}
+		driver.Rules = append(driver.Rules, rd)
+	}
+
+	run := sarifRun{
+		Tool:    sarifTool{Driver: driver},
+		Results: []sarifResult{},
+	}
+	invocation := sarifInvocation{ExecutionSuccessful: true}
+
+	for _, mr := range results {
+		if mr.Error != nil {
+			invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, sarifNotification{
+				Level:   "error",
+				Message: sarifMessage{Text: mr.Error.Error()},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: mr.URL}},
+				}},
+			})
+			continue
+		}
+		for _, item := range mr.Items {
+			idx, ok := ruleIndex[item.RuleName]
+			if !ok {
+				continue
+			}
+			ruleID := run.Tool.Driver.Rules[idx].ID
+			run.Results = append(run.Results, sarifItem(mr.URL, ruleID, idx, item))
+		}
+	}
+
+	run.Invocations = []sarifInvocation{invocation}
+	doc := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("sarif marshal error: %w", err)
+	}
+	_, _ = w.Write(data)
+	return nil
+}
+
+// sarifItem 将一条命中转换为 SARIF result。
+// 命中来自响应内容时 region 指向 URL 中的位置；来自 source map 原始源码时，URL 不带 region，
+// 原始文件中的位置记录在 relatedLocations 中；来自响应头或 URL 时不带 region，位置记录在 properties.location 中。
+// AI 判断记录在 properties.ai 中，被判断为误报的命中附带一条待复查（underReview）的 suppression。
+func sarifItem(url string, ruleID string, ruleIndex int, item matcher.MatchItem) sarifResult {
+	region := &sarifRegion{
+		StartLine:   item.Line,
+		StartColumn: item.Column,
+		ByteOffset:  &item.Offset,
+		ByteLength:  len(item.MatchedText),
+		Snippet:     &sarifMessage{Text: item.MatchedText},
+	}
+	var contextRegion *sarifRegion
+	if item.Context != "" {
+		contextRegion = &sarifRegion{Snippet: &sarifMessage{Text: item.Context}}
+	}
+
+	primary := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: url}}
+	var related []sarifLocation
+	if path := sourcePath(item); path != "" {
+		id := 1
+		related = append(related, sarifLocation{
+			ID: &id,
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: path},
+				Region:           region,
+				ContextRegion:    contextRegion,
+			},
+			Message: &sarifMessage{Text: "original source recovered from the source map"},
+		})
+	} else if item.Line > 0 && inBody(item) {
+		primary.Region = region
+		primary.ContextRegion = contextRegion
+	}
+
+	message := fmt.Sprintf("%s: %s", item.RuleName, item.Secret)
+	if item.Description != "" {
+		message += " - " + item.Description
+	}
+
+	properties := map[string]any{
+		"severity":   item.Severity,
+		"confidence": item.Confidence,
+		"secret":     item.Secret,
+		"entropy":    item.Entropy,
+	}
+	if !inBody(item) {
+		properties["target"] = item.Target
+		properties["location"] = item.Location
+	}
+	var suppressions []sarifSuppression
+	if v := item.AI; v != nil {
+		properties["ai"] = newJSONLVerdict(v)
+		if !v.TruePositive {
+			suppressions = append(suppressions, sarifSuppression{Kind: "external", Status: "underReview", Justification: "AI analysis: " + v.Reasoning})
+		}
+	}
+
+	return sarifResult{
+		RuleID:           ruleID,
+		RuleIndex:        ruleIndex,
+		Level:            sarifLevel(item.Severity),
+		Message:          sarifMessage{Text: message},
+		Locations:        []sarifLocation{{PhysicalLocation: primary}},
+		RelatedLocations: related,
+		Fingerprints:     map[string]string{sarifFingerprintKey: sarifFingerprint(ruleID, url, item)},
+		Suppressions:     suppressions,
+		Properties:       properties,
+	}
+}
+
+// inBody 判断命中是否来自响应内容（或 source map 原始源码），而不是响应头或 URL
+func inBody(item matcher.MatchItem) bool {
+	return item.Target == "" || item.Target == "body"
+}
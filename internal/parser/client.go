@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"SecureJS/config"
+	"SecureJS/internal/auth"
+	"SecureJS/internal/scope"
+)
+
+// Client 是二次请求共用的 HTTP 客户端：所有请求共用一个连接池，
+// 并按主机限制同时进行的请求数和每秒请求数，响应内容超过 MaxBodySize 时截断。
+// 范围外主机的链接（包括 source map 和重定向的目标）不会被请求，include / exclude 由调用方在收集链接时检查。
+// 可以被多个 goroutine 并发使用。
+type Client struct {
+	http          *http.Client
+	customHeaders []string
+	sess          *auth.Session
+	sc            *scope.Scope
+	maxBodySize   int64
+
+	perHostConcurrency int
+	interval           time.Duration // 同一主机两次请求之间的最小间隔，0 表示不限制
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+// hostLimiter 限制单个主机的并发数和请求速率
+type hostLimiter struct {
+	sem  chan struct{} // 为 nil 时不限制并发
+	mu   sync.Mutex
+	next time.Time // 下一个请求最早可以发出的时间
+}
+
+// NewClient 根据 http 配置创建 Client。忽略证书错误；proxy 不为空时使用代理；
+// sess 不为空时使用登录会话的 cookie jar；customHeaders 会加到每个请求上；sc 为 nil 时不限制扫描范围。
+func NewClient(cfg config.HTTP, customHeaders []string, proxy string, sess *auth.Session, sc *scope.Scope) *Client {
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(cfg.DialTimeout) * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	tr := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   time.Duration(cfg.TLSHandshakeTimeout) * time.Second,
+		ResponseHeaderTimeout: time.Duration(cfg.ResponseHeaderTimeout) * time.Second,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true, // 忽略证书错误
+		},
+	}
+
+	// 如果 proxy != "" 就设置代理
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err == nil {
+			tr.Proxy = http.ProxyURL(proxyURL)
+		} else {
+			log.Printf("[!] invalid proxy %s: %v", proxy, err)
+		}
+	}
+
+	c := &Client{
+		http: &http.Client{
+			Timeout:   time.Duration(cfg.Timeout) * time.Second,
+			Transport: tr,
+			Jar:       sess.Jar(),
+			// 重定向到范围外的链接时停止，与默认行为一样最多跟随 10 次
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 10 {
+					return fmt.Errorf("stopped after 10 redirects")
+				}
+				if !sc.AllowHost(req.URL.String()) {
+					return fmt.Errorf("redirect to %s is out of scope", req.URL)
+				}
+				return nil
+			},
+		},
+		customHeaders:      customHeaders,
+		sess:               sess,
+		sc:                 sc,
+		maxBodySize:        cfg.MaxBodySize,
+		perHostConcurrency: cfg.PerHostConcurrency,
+		hosts:              make(map[string]*hostLimiter),
+	}
+	if cfg.PerHostRate > 0 {
+		c.interval = time.Duration(float64(time.Second) / cfg.PerHostRate)
+	}
+	return c
+}
+
+// do 在主机限制下发送请求并读取最多 limit 字节的响应内容，truncated 表示内容被截断。
+// 返回的 resp 的 Body 已关闭，只用于读取状态码、响应头和最终 URL。
+func (c *Client) do(req *http.Request, limit int64) (resp *http.Response, body []byte, truncated bool, err error) {
+	if !c.sc.AllowHost(req.URL.String()) {
+		return nil, nil, false, fmt.Errorf("%s is out of scope", req.URL)
+	}
+
+	release := c.acquire(req.URL.Host)
+	defer release()
+
+	resp, err = c.http.Do(req)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to GET %s: %w", req.URL, err)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			log.Printf("[!] failed to close response body for %s: %v", req.URL, cerr)
+		}
+	}()
+
+	// 多读一个字节，用于判断内容是否超过限制
+	body, err = io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to read body from %s: %w", req.URL, err)
+	}
+	if int64(len(body)) > limit {
+		body = body[:limit]
+		truncated = true
+	}
+	return resp, body, truncated, nil
+}
+
+// acquire 等待 host 的并发名额和速率限制，返回释放名额的函数。
+// 先占用名额，再以占到名额的时刻为准检查速率限制：还没到下一个请求的时间时先让出名额等待，
+// 醒来后重新占用名额并再次检查，这样等待期间不占用名额，排队的请求也不会在名额释放后连续发出
+func (c *Client) acquire(host string) func() {
+	hl := c.limiter(host)
+	release := func() {
+		if hl.sem != nil {
+			<-hl.sem
+		}
+	}
+	for {
+		if hl.sem != nil {
+			hl.sem <- struct{}{}
+		}
+		if c.interval <= 0 {
+			return release
+		}
+		hl.mu.Lock()
+		now := time.Now()
+		if !hl.next.After(now) {
+			hl.next = now.Add(c.interval)
+			hl.mu.Unlock()
+			return release
+		}
+		wait := hl.next.Sub(now)
+		hl.mu.Unlock()
+		release()
+		time.Sleep(wait)
+	}
+}
+
+// limiter 返回 host 对应的 hostLimiter，不存在时创建
+func (c *Client) limiter(host string) *hostLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hl, ok := c.hosts[host]
+	if !ok {
+		hl = &hostLimiter{}
+		if c.perHostConcurrency > 0 {
+			hl.sem = make(chan struct{}, c.perHostConcurrency)
+		}
+		c.hosts[host] = hl
+	}
+	return hl
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquireRate(t *testing.T) {
+	const (
+		interval = 40 * time.Millisecond
+		requests = 6
+	)
+	c := &Client{perHostConcurrency: 2, interval: interval, hosts: make(map[string]*hostLimiter)}
+	// 前两个请求同时在 holdUntil 释放名额，此时排队的请求也不能连续发出
+	holdUntil := time.Now().Add(8 * interval)
+
+	var (
+		mu      sync.Mutex
+		started []time.Time
+		active  int
+		peak    int
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release := c.acquire("a.example.com")
+			mu.Lock()
+			started = append(started, time.Now())
+			active++
+			peak = max(peak, active)
+			mu.Unlock()
+
+			time.Sleep(max(time.Until(holdUntil), interval))
+
+			mu.Lock()
+			active--
+			mu.Unlock()
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak)
+	}
+	sort.Slice(started, func(i, j int) bool { return started[i].Before(started[j]) })
+	for i := 1; i < len(started); i++ {
+		// 留出少量计时误差
+		if gap := started[i].Sub(started[i-1]); gap < interval-5*time.Millisecond {
+			t.Errorf("request %d started %v after the previous one, want at least %v", i, gap, interval)
+		}
+	}
+}
+
+func TestAcquireUnlimited(t *testing.T) {
+	c := &Client{hosts: make(map[string]*hostLimiter)}
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			c.acquire("a.example.com")()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("acquire blocked without any limit")
+	}
+}
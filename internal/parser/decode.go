@@ -0,0 +1,225 @@
+package parser
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// acceptEncoding 是二次请求声明支持的压缩格式。手动设置后 http.Transport 不再自动解压 gzip，
+// 所有压缩格式统一由 decompress 处理。
+const acceptEncoding = "gzip, deflate, br, zstd"
+
+// maxDecodeRounds 限制没有 Content-Encoding 声明时按魔数嗅探解压的层数（如二次 gzip 的资源）
+const maxDecodeRounds = 3
+
+// metaCharsetRegex 匹配 <meta charset="gbk"> 和 <meta http-equiv="Content-Type" content="text/html; charset=gbk">
+var metaCharsetRegex = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([\w.:-]+)`)
+
+// metaSniffSize 是查找 <meta charset> 时扫描的前缀长度
+const metaSniffSize = 4096
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	utf8BOM   = []byte{0xef, 0xbb, 0xbf}
+)
+
+// decodeBody 将原始响应内容解压并转换为 UTF-8，写入 pr.Body，同时记录 pr.ContentType 和 pr.Encoding。
+// 解压后超过 limit 的内容会被截断并设置 pr.Truncated。
+func decodeBody(pr *ParseResult, header http.Header, raw []byte, limit int64) {
+	data, truncated := decompress(pr.URL, header.Get("Content-Encoding"), raw, limit)
+	if truncated {
+		pr.Truncated = true
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	pr.ContentType = mediaType
+
+	pr.Encoding, pr.Body = decodeCharset(pr.URL, data, mediaType, params["charset"])
+	pr.Body = strings.TrimSpace(pr.Body)
+}
+
+// FromBytes 将不是通过二次请求得到的内容（如本地文件、导入的流量记录）转换为 ParseResult，
+// 与二次请求一样解压并转换字符集，超过 limit 的部分会被截断；内容本身是 source map 时还原其中的原始源码。
+// header 为内容对应的响应头，可以为空。
+func FromBytes(urlStr string, header http.Header, data []byte, limit int64) *ParseResult {
+	if header == nil {
+		header = http.Header{}
+	}
+	pr := &ParseResult{URL: urlStr, Header: header}
+	if int64(len(data)) > limit {
+		data = data[:limit]
+		pr.Truncated = true
+	}
+	decodeBody(pr, header, data, limit)
+	if IsSourceMap(pr.URL, pr.Body) {
+		if files, err := UnpackSourceMap([]byte(pr.Body)); err == nil {
+			pr.Sources = files
+		}
+	}
+	return pr
+}
+
+// decompress 按 Content-Encoding 逆序解压，之后再按魔数嗅探没有声明的 gzip / zstd 压缩
+// （如二次 gzip 或直接托管 .gz 文件），以 .br 结尾的 URL 在内容不是文本时尝试 brotli 解压。
+// 某一层解压失败时保留该层之前的内容。
+func decompress(urlStr string, contentEncoding string, data []byte, limit int64) ([]byte, bool) {
+	truncated := false
+
+	codings := strings.Split(contentEncoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		if coding == "" || coding == "identity" {
+			continue
+		}
+		out, cut, err := decompressOnce(coding, data, limit)
+		if err != nil {
+			log.Printf("[!] %s: failed to decode %s content: %v", urlStr, coding, err)
+			return data, truncated
+		}
+		data, truncated = out, truncated || cut
+	}
+
+	for round := 0; round < maxDecodeRounds; round++ {
+		var coding string
+		switch {
+		case bytes.HasPrefix(data, gzipMagic):
+			coding = "gzip"
+		case bytes.HasPrefix(data, zstdMagic):
+			coding = "zstd"
+		case round == 0 && strings.HasSuffix(strings.ToLower(strings.Split(urlStr, "?")[0]), ".br") && !utf8.Valid(data):
+			coding = "br"
+		default:
+			return data, truncated
+		}
+		out, cut, err := decompressOnce(coding, data, limit)
+		if err != nil {
+			return data, truncated
+		}
+		data, truncated = out, truncated || cut
+	}
+	return data, truncated
+}
+
+// decompressOnce 用 coding 对应的格式解压一层，最多读取 limit 字节
+func decompressOnce(coding string, data []byte, limit int64) ([]byte, bool, error) {
+	var r io.Reader
+	switch coding {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, false, err
+		}
+		defer zr.Close()
+		r = zr
+	case "deflate":
+		// 按规范 deflate 是 zlib 格式，但部分服务器直接返回裸 deflate 数据
+		if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+			defer zr.Close()
+			r = zr
+		} else {
+			fr := flate.NewReader(bytes.NewReader(data))
+			defer fr.Close()
+			r = fr
+		}
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(data))
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, false, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, false, fmt.Errorf("unsupported content encoding")
+	}
+
+	out, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(out)) > limit {
+		return out[:limit], true, nil
+	}
+	return out, false, nil
+}
+
+// decodeCharset 确定内容的字符集并转换为 UTF-8，返回字符集名称和转换后的内容。
+// 优先级：BOM > Content-Type 中的 charset > HTML 中的 <meta charset> > 默认 UTF-8。
+func decodeCharset(urlStr string, data []byte, mediaType string, headerCharset string) (string, string) {
+	label, fromBOM := "", false
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return "utf-8", string(data[len(utf8BOM):])
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		label, fromBOM = "utf-16le", true
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		label, fromBOM = "utf-16be", true
+	case headerCharset != "":
+		label = headerCharset
+	case strings.Contains(mediaType, "html") || strings.Contains(mediaType, "xml"):
+		head := data
+		if len(head) > metaSniffSize {
+			head = head[:metaSniffSize]
+		}
+		if m := metaCharsetRegex.FindSubmatch(head); m != nil {
+			label = string(m[1])
+		}
+	}
+	if label == "" {
+		return "utf-8", string(data)
+	}
+
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		log.Printf("[!] %s: unknown charset %s, treated as utf-8", urlStr, label)
+		return "utf-8", string(data)
+	}
+	name, _ := htmlindex.Name(enc)
+	if name == "utf-8" {
+		return name, string(data)
+	}
+	// 声明的字符集与内容不符：包含非 ASCII 字符且是合法 UTF-8 的内容几乎不可能是 GBK 等编码，
+	// 常见于已经解码过的内容（如 HAR 中的响应）或声明错误的服务器
+	if !fromBOM && utf8.Valid(data) && hasNonASCII(data) {
+		return "utf-8", string(data)
+	}
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		log.Printf("[!] %s: failed to decode %s content: %v", urlStr, name, err)
+		return name, string(data)
+	}
+	return name, strings.TrimPrefix(string(out), "\ufeff")
+}
+
+// hasNonASCII 判断内容中是否有非 ASCII 字节
+func hasNonASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const plainJS = `var apiKey = "x7Kq9mZp2LvR4tWn";`
+
+// compress 用 coding 对应的格式压缩 data
+func compress(t *testing.T, coding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch coding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "flate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = fw
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = zw
+	default:
+		t.Fatalf("unknown coding %s", coding)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	plain := []byte(plainJS)
+	tests := []struct {
+		name          string
+		url           string
+		encoding      string
+		data          []byte
+		limit         int64
+		want          string
+		wantTruncated bool
+	}{
+		{"identity", "https://a.com/app.js", "", plain, 1 << 20, plainJS, false},
+		{"gzip", "https://a.com/app.js", "gzip", compress(t, "gzip", plain), 1 << 20, plainJS, false},
+		{"x-gzip", "https://a.com/app.js", "x-gzip", compress(t, "gzip", plain), 1 << 20, plainJS, false},
+		{"deflate zlib", "https://a.com/app.js", "deflate", compress(t, "zlib", plain), 1 << 20, plainJS, false},
+		{"deflate raw", "https://a.com/app.js", "deflate", compress(t, "flate", plain), 1 << 20, plainJS, false},
+		{"br", "https://a.com/app.js", "br", compress(t, "br", plain), 1 << 20, plainJS, false},
+		{"zstd", "https://a.com/app.js", "ZSTD", compress(t, "zstd", plain), 1 << 20, plainJS, false},
+		{"stacked encodings", "https://a.com/app.js", "gzip, br", compress(t, "br", compress(t, "gzip", plain)), 1 << 20, plainJS, false},
+		{"double gzip", "https://a.com/app.js", "gzip", compress(t, "gzip", compress(t, "gzip", plain)), 1 << 20, plainJS, false},
+		{"undeclared zstd", "https://a.com/app.js", "", compress(t, "zstd", plain), 1 << 20, plainJS, false},
+		{"br file", "https://a.com/app.js.br?v=1", "", compress(t, "br", plain), 1 << 20, plainJS, false},
+		{"truncated", "https://a.com/app.js", "gzip", compress(t, "gzip", plain), 10, plainJS[:10], true},
+		{"wrong encoding", "https://a.com/app.js", "gzip", plain, 1 << 20, plainJS, false},
+		{"unsupported encoding", "https://a.com/app.js", "compress", plain, 1 << 20, plainJS, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := decompress(tt.url, tt.encoding, tt.data, tt.limit)
+			if string(got) != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("decompress() = %q, %v, want %q, %v", got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestDecodeCharset(t *testing.T) {
+	gbk := []byte{0xd6, 0xd0, 0xce, 0xc4} // "中文"
+	tests := []struct {
+		name          string
+		data          []byte
+		mediaType     string
+		headerCharset string
+		wantName      string
+		wantText      string
+	}{
+		{"default utf-8", []byte("中文"), "application/javascript", "", "utf-8", "中文"},
+		{"utf-8 bom", append([]byte{0xef, 0xbb, 0xbf}, "中文"...), "text/html", "gbk", "utf-8", "中文"},
+		{"utf-16le bom", []byte{0xff, 0xfe, 'h', 0, 'i', 0}, "text/plain", "", "utf-16le", "hi"},
+		{"utf-16be bom", []byte{0xfe, 0xff, 0, 'h', 0, 'i'}, "text/plain", "", "utf-16be", "hi"},
+		{"header charset", gbk, "application/javascript", "GBK", "gbk", "中文"},
+		{"header gb2312 alias", gbk, "text/html", "gb2312", "gbk", "中文"},
+		{"meta charset", append([]byte(`<meta charset="gbk">`), gbk...), "text/html", "", "gbk", `<meta charset="gbk">中文`},
+		{"meta http-equiv", append([]byte(`<meta http-equiv="Content-Type" content="text/html; charset=gbk">`), gbk...), "text/html", "", "gbk", `<meta http-equiv="Content-Type" content="text/html; charset=gbk">中文`},
+		{"meta ignored for js", append([]byte(`<meta charset="gbk">`), gbk...), "application/javascript", "", "utf-8", `<meta charset="gbk">` + string(gbk)},
+		{"declared charset but utf-8 content", []byte("中文"), "text/html", "gbk", "utf-8", "中文"},
+		{"unknown charset", []byte("abc"), "text/html", "x-unknown", "utf-8", "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, text := decodeCharset("https://a.com/", tt.data, tt.mediaType, tt.headerCharset)
+			if name != tt.wantName || text != tt.wantText {
+				t.Errorf("decodeCharset() = %q, %q, want %q, %q", name, text, tt.wantName, tt.wantText)
+			}
+		})
+	}
+}
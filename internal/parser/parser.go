@@ -1,105 +1,99 @@
 package parser
 
 import (
-	"crypto/tls"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"strings"
-	"sync"
-	"time"
 )
 
 // ParseResult 用于存储对单个 URL 做二次请求的结果
 type ParseResult struct {
-	URL        string // 当前目标 URL
-	StatusCode int    // HTTP 状态码
-	Body       string // 响应内容（纯文本/HTML/JSON 等）
-	Error      error  // 如果请求失败或解析失败，则记录错误
+	URL        string      // 当前目标 URL
+	StatusCode int         // HTTP 状态码
+	Header     http.Header // 响应头，规则的 target 为 headers 或 all 时参与匹配
+	Body       string      // 响应内容（纯文本/HTML/JSON 等），已解压并转换为 UTF-8
+	Truncated  bool        // 响应内容超过 http.max_body_size，Body 只包含前面的部分
+	Error      error       // 如果请求失败或解析失败，则记录错误
+
+	ContentType string // 响应的媒体类型（如 text/html），响应头中没有时根据内容推断
+	Encoding    string // 检测到的原始字符集（如 utf-8、gbk、shift_jis）
+
+	SourceMapURL string       // JS 声明的 source map 地址（如果有）
+	Sources      []SourceFile // 从 source map 的 sourcesContent 中还原出的原始源码
 }
 
-// ParseAll 并发请求一批 URLs，并返回每个 URL 的响应内容。
-// concurrency 用于控制并发线程数。
-func ParseAll(urls []string, concurrency int, customHeaders []string, proxy string) ([]*ParseResult, error) {
-	if len(urls) == 0 {
-		return nil, fmt.Errorf("no URLs to parse")
+// Fetch 请求单个 URL 并返回响应内容，请求失败时错误记录在返回结果的 Error 中
+func (c *Client) Fetch(urlStr string) *ParseResult {
+	res, err := c.parseOneURL(urlStr)
+	if err != nil {
+		return &ParseResult{
+			URL:   urlStr,
+			Error: err,
+		}
 	}
-	if concurrency <= 0 {
-		concurrency = 1
+	return res
+}
+
+// parseOneURL 对单个 URL 发起请求，获取响应内容。
+// 如果响应表明登录会话已失效，会重新执行登录流程后再请求一次。
+func (c *Client) parseOneURL(urlStr string) (*ParseResult, error) {
+	generation := c.sess.Generation()
+	result, resp, err := c.fetchOnce(urlStr)
+	if err != nil {
+		return nil, err
 	}
 
-    // 准备结果通道
-    resultChan := make(chan *ParseResult, len(urls))
-
-    // 并发控制 - 使用有缓冲的通道作为信号量
-    sem := make(chan struct{}, concurrency)
-
-    // 初始化 WaitGroup
-    var wg sync.WaitGroup
-
-    // 启动 goroutine 处理每个 URL
-    for _, targetURL := range urls {
-        wg.Add(1)
-
-        go func(url string) {
-            defer wg.Done()
-
-            // 获取一个信号，限制并发数量
-            sem <- struct{}{}
-            defer func() { <-sem }()
-
-            // 执行 URL 处理
-            res, err := parseOneURL(url, customHeaders, proxy)
-            if err != nil {
-                resultChan <- &ParseResult{
-                    URL:   url,
-                    Error: err,
-                }
-                return
-            }
-            resultChan <- res
-        }(targetURL)
-    }
-
-    // 等待所有 goroutine 完成
-    wg.Wait()
-    close(resultChan)
-
-	// 收集结果
-	var results []*ParseResult
-	for r := range resultChan {
-		results = append(results, r)
+	if c.sess.Expired(resp.StatusCode, resp.Request.URL.String(), result.Body) {
+		if err := c.sess.Refresh(generation); err != nil {
+			log.Printf("[!] %s: %v", urlStr, err)
+		} else if result, resp, err = c.fetchOnce(urlStr); err != nil {
+			return nil, err
+		}
+	}
+	if result.Truncated {
+		log.Printf("[!] %s: response body exceeds %d bytes, truncated", urlStr, c.maxBodySize)
 	}
 
-	return results, nil
+	// 如果是 JS 且暴露了 source map，或本身就是 source map，还原其中的原始源码
+	c.attachSourceMap(result, resp.Header)
+
+	return result, nil
 }
 
-// parseOneURL 对单个 URL 发起请求，获取响应内容。
-func parseOneURL(urlStr string, customHeaders []string, proxy string) (*ParseResult, error) {
-	// 自定义 Transport，忽略证书错误
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true, // 忽略证书错误
-		},
+// fetchOnce 发起一次 GET 请求并读取响应内容，返回的 resp 的 Body 已关闭，只用于读取响应头和最终 URL
+func (c *Client) fetchOnce(urlStr string) (*ParseResult, *http.Response, error) {
+	// 手动创建请求，以便设置 UA 和其他伪装头
+	req, err := newRequest(urlStr, c.customHeaders)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 如果 proxy != "" 就设置代理
-	if proxy != "" {
-		proxyURL, err := url.Parse(proxy)
-		if err == nil {
-			tr.Proxy = http.ProxyURL(proxyURL)
-		}
+	resp, bodyBytes, truncated, err := c.do(req, c.maxBodySize)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 使用自定义 Transport
-	client := &http.Client{
-		Timeout:   10 * time.Second, 
-		Transport: tr,
+	result := &ParseResult{
+		URL:        urlStr,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Truncated:  truncated,
 	}
+	// 按 Content-Encoding 解压，并按 BOM / Content-Type / <meta charset> 转换为 UTF-8
+	decodeBody(result, resp.Header, bodyBytes, c.maxBodySize)
 
-	// 手动创建请求，以便设置 UA 和其他伪装头
+	return result, resp, nil
+}
+
+// ResolveSourceMap 为不是由 Fetch 请求得到的响应（如无头浏览器捕获的响应）还原 source map，
+// header 为该响应的响应头。
+func (c *Client) ResolveSourceMap(pr *ParseResult, header http.Header) {
+	c.attachSourceMap(pr, header)
+}
+
+// newRequest 创建带伪装 UA 和自定义请求头的 GET 请求
+func newRequest(urlStr string, customHeaders []string) (*http.Request, error) {
 	req, err := http.NewRequest("GET", urlStr, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request for %s: %w", urlStr, err)
@@ -108,6 +102,7 @@ func parseOneURL(urlStr string, customHeaders []string, proxy string) (*ParseRes
 	// 设置伪装头
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) "+
 		"AppleWebKit/537.36 (KHTML, like Gecko) Chrome/95.0.4638.69 Safari/537.36")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
 
 	// 自定义请求头
 	for _, h := range customHeaders {
@@ -118,29 +113,5 @@ func parseOneURL(urlStr string, customHeaders []string, proxy string) (*ParseRes
 			req.Header.Set(key, val)
 		}
 	}
-
-	// 发起请求
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to GET %s: %w", urlStr, err)
-	}
-	defer func() {
-		if cerr := resp.Body.Close(); cerr != nil {
-			log.Printf("[!] failed to close response body for %s: %v", urlStr, cerr)
-		}
-	}()
-
-	// 读取响应体
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read body from %s: %w", urlStr, err)
-	}
-
-	body := strings.TrimSpace(string(bodyBytes))
-
-	return &ParseResult{
-		URL:        urlStr,
-		StatusCode: resp.StatusCode,
-		Body:       body,
-	}, nil
+	return req, nil
 }
\ No newline at end of file
@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// SourceFile 表示从 source map 的 sourcesContent 中还原出来的一个原始源码文件
+type SourceFile struct {
+	Path    string // 原始文件路径，如 webpack:///src/config/aws.ts
+	Content string // 原始文件内容
+}
+
+// sourceMap 是 source map v3 中我们关心的字段
+type sourceMap struct {
+	Version        int       `json:"version"`
+	SourceRoot     string    `json:"sourceRoot"`
+	Sources        []string  `json:"sources"`
+	SourcesContent []*string `json:"sourcesContent"`
+}
+
+// sourceMappingURLRegex 匹配 JS 末尾的 //# sourceMappingURL=xxx（旧写法为 //@）
+var sourceMappingURLRegex = regexp.MustCompile(`(?m)^[ \t]*//[#@][ \t]*sourceMappingURL=(\S+)[ \t]*$`)
+
+// maxSourceMapSize 限制单个 source map 的大小，避免异常大的文件占满内存
+const maxSourceMapSize = 50 << 20
+
+// IsSourceMap 粗略判断内容是否是一个 source map
+func IsSourceMap(urlStr string, body string) bool {
+	if strings.HasSuffix(strings.ToLower(strings.Split(urlStr, "?")[0]), ".map") {
+		return true
+	}
+	head := body
+	if len(head) > 64 {
+		head = head[:64]
+	}
+	return strings.HasPrefix(head, "{") && strings.Contains(strings.ReplaceAll(head, " ", ""), `"version":3`)
+}
+
+// UnpackSourceMap 解析 source map，返回 sourcesContent 中的所有原始源码文件
+func UnpackSourceMap(data []byte) ([]SourceFile, error) {
+	// 部分 source map 以 )]}' 开头防止 XSSI
+	data = []byte(strings.TrimPrefix(string(data), ")]}'"))
+
+	var sm sourceMap
+	if err := json.Unmarshal(data, &sm); err != nil {
+		return nil, fmt.Errorf("invalid source map: %w", err)
+	}
+
+	var files []SourceFile
+	for i, src := range sm.Sources {
+		if i >= len(sm.SourcesContent) || sm.SourcesContent[i] == nil {
+			continue
+		}
+		files = append(files, SourceFile{
+			Path:    cleanSourcePath(sm.SourceRoot, src),
+			Content: *sm.SourcesContent[i],
+		})
+	}
+	return files, nil
+}
+
+// attachSourceMap 如果 JS 响应通过 SourceMap 头或 sourceMappingURL 注释声明了 source map，
+// 就请求该 source map 并把还原出的原始源码放到 pr.Sources；
+// 如果 pr 本身就是一个 source map，则直接解包它自己的内容。
+func (c *Client) attachSourceMap(pr *ParseResult, header http.Header) {
+	if IsSourceMap(pr.URL, pr.Body) {
+		if files, err := UnpackSourceMap([]byte(pr.Body)); err == nil {
+			pr.Sources = files
+		}
+		return
+	}
+
+	mapRef := header.Get("SourceMap")
+	if mapRef == "" {
+		mapRef = header.Get("X-SourceMap")
+	}
+	if mapRef == "" {
+		if m := sourceMappingURLRegex.FindAllStringSubmatch(pr.Body, -1); len(m) > 0 {
+			mapRef = m[len(m)-1][1]
+		}
+	}
+	if mapRef == "" {
+		return
+	}
+
+	data, mapURL, err := c.fetchSourceMap(pr.URL, mapRef)
+	if err != nil {
+		return
+	}
+	files, err := UnpackSourceMap(data)
+	if err != nil {
+		return
+	}
+	pr.SourceMapURL = mapURL
+	pr.Sources = files
+}
+
+// fetchSourceMap 获取 source map 内容，支持内联的 data: URI
+func (c *Client) fetchSourceMap(jsURL string, mapRef string) ([]byte, string, error) {
+	if strings.HasPrefix(mapRef, "data:") {
+		comma := strings.Index(mapRef, ",")
+		if comma == -1 {
+			return nil, "", fmt.Errorf("invalid inline source map")
+		}
+		meta, payload := mapRef[:comma], mapRef[comma+1:]
+		if strings.HasSuffix(meta, ";base64") {
+			data, err := base64.StdEncoding.DecodeString(payload)
+			return data, jsURL, err
+		}
+		unescaped, err := url.PathUnescape(payload)
+		return []byte(unescaped), jsURL, err
+	}
+
+	base, err := url.Parse(jsURL)
+	if err != nil {
+		return nil, "", err
+	}
+	ref, err := url.Parse(mapRef)
+	if err != nil {
+		return nil, "", err
+	}
+	mapURL := base.ResolveReference(ref).String()
+
+	req, err := newRequest(mapURL, c.customHeaders)
+	if err != nil {
+		return nil, mapURL, err
+	}
+	resp, data, _, err := c.do(req, maxSourceMapSize)
+	if err != nil {
+		return nil, mapURL, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, mapURL, fmt.Errorf("source map %s returned status %d", mapURL, resp.StatusCode)
+	}
+	data, _ = decompress(mapURL, resp.Header.Get("Content-Encoding"), data, maxSourceMapSize)
+	return data, mapURL, nil
+}
+
+// cleanSourcePath 拼接 sourceRoot 与 source，并去掉 webpack:///./src 中多余的 ./
+func cleanSourcePath(root string, src string) string {
+	p := src
+	if root != "" && !strings.Contains(src, "://") {
+		p = strings.TrimSuffix(root, "/") + "/" + strings.TrimPrefix(src, "/")
+	}
+	p = strings.ReplaceAll(p, "/./", "/")
+	return p
+}
@@ -0,0 +1,342 @@
+package pipeline
+
+import (
+	"log"
+	"runtime"
+	"strings"
+	"sync"
+
+	"SecureJS/internal/auth"
+	"SecureJS/internal/crawler"
+	"SecureJS/internal/headless"
+	"SecureJS/internal/matcher"
+	"SecureJS/internal/parser"
+	"SecureJS/internal/scope"
+	"SecureJS/internal/utils"
+
+	"github.com/go-rod/rod"
+)
+
+// Options 是流水线各阶段共用的参数
+type Options struct {
+	Threads       int            // 浏览器与 HTTP 请求阶段各自的并发数
+	Depth         int            // 爬取层数，1 表示只爬种子
+	MaxPages      int            // 本次运行最多爬取的页面/JS 数量，<=0 表示不限制
+	BrowserPath   string         // Chrome/Chromium 路径，为空时使用 Rod 默认的浏览器
+	CustomHeaders []string       // -H 传入的自定义请求头
+	Proxy         string         // 浏览器使用的代理，HTTP 请求的代理在创建 Client 时设置
+	Client        *parser.Client // 请求阶段和 source map 共用的 HTTP 客户端
+	Scope         *scope.Scope
+	Session       *auth.Session
+}
+
+// task 是一个待处理的链接
+type task struct {
+	url        string
+	level      int                 // 所在层数，种子为 1
+	chunkRound int                 // 经过几轮懒加载 chunk 发现得到，普通链接为 0
+	crawl      bool                // 是否需要从响应内容中继续提取链接（消耗页面预算）
+	browser    bool                // 是否需要先用无头浏览器加载
+	captured   *parser.ParseResult // 浏览器已经捕获到的响应，请求阶段直接复用
+}
+
+// discovery 是某个阶段发现的新链接
+type discovery struct {
+	url        string
+	level      int
+	chunkRound int
+	captured   *parser.ParseResult
+	seed       bool
+	request    bool // 浏览器加载页面时发出的请求
+}
+
+// event 是浏览器/请求阶段处理完一个 task 后交给协调者的结果
+type event struct {
+	task        *task
+	browserDone bool // true 表示浏览器阶段完成，task 接下来进入请求阶段
+	found       []discovery
+}
+
+// Run 以 seeds 为起点运行 爬取 → 请求 → 匹配 流水线，各阶段之间用有界通道连接：
+//   - 协调者 goroutine 持有待处理链接队列和去重集合，按层数从小到大派发 task，
+//     各阶段发现的链接都交给它，阶段之间不会因为互相等待而死锁；
+//   - 浏览器阶段加载页面，捕获到的响应直接交给请求阶段复用；
+//   - 请求阶段获取响应内容，提取链接和懒加载 chunk 后立即交给匹配阶段；
+//   - 匹配阶段逐个匹配，结果通过 emit 输出，响应内容随后即可释放。
+//
+// 下游变慢时有界通道会阻塞上游，形成背压。emit 会被多个 goroutine 并发调用。
+func Run(seeds []string, opts Options, m *matcher.Matcher, emit func(*matcher.MatchResult)) {
+	if opts.Threads <= 0 {
+		opts.Threads = 1
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = 1
+	}
+
+	browserJobs := make(chan *task, opts.Threads)
+	fetchJobs := make(chan *task, opts.Threads)
+	matchJobs := make(chan *parser.ParseResult, opts.Threads)
+	events := make(chan event, opts.Threads*2)
+
+	// 浏览器只在第一次需要时启动
+	var (
+		browser     *rod.Browser
+		browserOnce sync.Once
+	)
+	getBrowser := func() *rod.Browser {
+		browserOnce.Do(func() {
+			browser = headless.Launch(opts.BrowserPath, opts.Proxy)
+		})
+		return browser
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < opts.Threads; i++ {
+		workers.Add(2)
+		go func() {
+			defer workers.Done()
+			for t := range browserJobs {
+				events <- browse(getBrowser(), t, opts)
+			}
+		}()
+		go func() {
+			defer workers.Done()
+			for t := range fetchJobs {
+				pr, ev := fetch(t, opts)
+				events <- ev
+				if pr != nil {
+					matchJobs <- pr
+				}
+			}
+		}()
+	}
+
+	matched := make(chan struct{})
+	go func() {
+		m.MatchStream(matchJobs, runtime.NumCPU(), emit)
+		close(matched)
+	}()
+
+	coordinate(seeds, opts, browserJobs, fetchJobs, matchJobs, events)
+
+	close(browserJobs)
+	close(fetchJobs)
+	workers.Wait()
+	close(matchJobs)
+	<-matched
+
+	if browser != nil {
+		if err := browser.Close(); err != nil {
+			log.Printf("[!] failed to close browser: %v\n", err)
+		}
+	}
+}
+
+// coordinate 是协调者的主循环：持有待处理链接队列，把 task 派发给浏览器/请求阶段，
+// 并接收各阶段发现的新链接，直到队列为空且没有正在处理的 task。
+// 协调者在同一个 select 中既派发又接收，所以工作 goroutine 向它发送结果时不会永久阻塞。
+// 只需匹配 URL 的请求由协调者直接交给匹配阶段。
+func coordinate(seeds []string, opts Options, browserJobs chan<- *task, fetchJobs chan<- *task, matchJobs chan<- *parser.ParseResult, events <-chan event) {
+	c := &coordinator{
+		opts:    opts,
+		seen:    make(map[string]*task),
+		pending: make(map[int][]*task),
+	}
+	for _, seed := range seeds {
+		c.discover(discovery{url: seed, level: 1, seed: true})
+	}
+
+	inFlight := 0
+	for inFlight > 0 || c.queued() > 0 || len(c.urlOnly) > 0 {
+		next := c.peek()
+		var browserCh, fetchCh chan<- *task
+		if next != nil {
+			if next.browser {
+				browserCh = browserJobs
+			} else {
+				fetchCh = fetchJobs
+			}
+		}
+		var matchCh chan<- *parser.ParseResult
+		var nextURL *parser.ParseResult
+		if len(c.urlOnly) > 0 {
+			matchCh = matchJobs
+			nextURL = c.urlOnly[0]
+		}
+
+		select {
+		case browserCh <- next:
+			c.pop()
+			inFlight++
+		case fetchCh <- next:
+			c.pop()
+			c.dispatched(next)
+			inFlight++
+		case matchCh <- nextURL:
+			c.urlOnly[0] = nil
+			c.urlOnly = c.urlOnly[1:]
+		case ev := <-events:
+			inFlight--
+			for _, d := range ev.found {
+				c.discover(d)
+			}
+			// 浏览器阶段完成后，同一个 task 继续进入请求阶段
+			if ev.browserDone {
+				ev.task.browser = false
+				c.push(ev.task)
+			}
+		}
+	}
+
+	log.Printf("[*] Crawled %d page(s)/JS file(s), processed %d URL(s)", c.crawled, len(c.seen))
+}
+
+// coordinator 保存流水线的全局状态，只在协调者 goroutine 中访问
+type coordinator struct {
+	opts    Options
+	seen    map[string]*task      // 已发现的链接（去掉末尾 /），值在 task 派发到请求阶段后置为 nil
+	pending map[int][]*task       // 按层数分组的待派发 task
+	urlOnly []*parser.ParseResult // 不需要请求、只匹配 URL 的链接
+	crawled int                   // 已消耗的页面预算
+	warned  bool
+}
+
+// discover 处理一个新发现的链接：去重、判断是否需要爬取和浏览器加载，然后加入队列。
+// 浏览器捕获到的响应会挂到尚未进入请求阶段的同一链接上。
+func (c *coordinator) discover(d discovery) {
+	key := strings.TrimSuffix(d.url, "/")
+	if !d.seed && !crawler.ShouldCollect(d.url, c.opts.Scope) {
+		// 浏览器发出的图片、统计等请求不需要获取内容，但 URL 的查询参数中仍可能带有 token，只匹配 URL
+		if _, exists := c.seen[key]; d.request && !exists && c.opts.Scope.Allow(d.url) {
+			c.seen[key] = nil
+			c.urlOnly = append(c.urlOnly, &parser.ParseResult{URL: d.url})
+		}
+		return
+	}
+	if t, exists := c.seen[key]; exists {
+		if t != nil && t.captured == nil && d.captured != nil {
+			t.captured = d.captured
+		}
+		return
+	}
+
+	t := &task{
+		url:        d.url,
+		level:      d.level,
+		chunkRound: d.chunkRound,
+		captured:   d.captured,
+	}
+	// 种子总是爬取；之后的层只有页面和 JS 文件需要继续爬取，HTML 页面还需要浏览器加载
+	if d.seed || (d.level <= c.opts.Depth && (utils.IsPageURL(d.url) || utils.IsScriptURL(d.url))) {
+		if c.opts.MaxPages > 0 && c.crawled >= c.opts.MaxPages {
+			if !c.warned {
+				log.Printf("[!] Page budget of %d reached, newly found links are scanned but not crawled", c.opts.MaxPages)
+				c.warned = true
+			}
+		} else {
+			c.crawled++
+			t.crawl = true
+			t.browser = d.seed || utils.IsPageURL(d.url)
+		}
+	}
+
+	c.seen[key] = t
+	c.push(t)
+}
+
+// dispatched 标记 task 已经进入请求阶段，之后捕获到的同一链接的响应不再需要
+func (c *coordinator) dispatched(t *task) {
+	c.seen[strings.TrimSuffix(t.url, "/")] = nil
+}
+
+func (c *coordinator) push(t *task) {
+	c.pending[t.level] = append(c.pending[t.level], t)
+}
+
+// peek 返回层数最小的待派发 task，没有时返回 nil
+func (c *coordinator) peek() *task {
+	level := c.minLevel()
+	if level < 0 {
+		return nil
+	}
+	return c.pending[level][0]
+}
+
+func (c *coordinator) pop() {
+	level := c.minLevel()
+	queue := c.pending[level]
+	queue[0] = nil
+	if len(queue) == 1 {
+		delete(c.pending, level)
+	} else {
+		c.pending[level] = queue[1:]
+	}
+}
+
+func (c *coordinator) minLevel() int {
+	level := -1
+	for l := range c.pending {
+		if level < 0 || l < level {
+			level = l
+		}
+	}
+	return level
+}
+
+func (c *coordinator) queued() int {
+	n := 0
+	for _, q := range c.pending {
+		n += len(q)
+	}
+	return n
+}
+
+// browse 是浏览器阶段：加载页面，收集页面发出的请求和捕获到的响应
+func browse(browser *rod.Browser, t *task, opts Options) event {
+	ev := event{task: t, browserDone: true}
+	result, err := crawler.CrawlPage(browser, t.url, opts.Client, opts.CustomHeaders, opts.Scope, opts.Session)
+	if err != nil {
+		log.Printf("[!] URL: %s, Error: %v\n", t.url, err)
+		return ev
+	}
+	// 先登记捕获到的响应，再登记请求的链接，这样同一链接的请求阶段可以直接复用响应
+	for _, pr := range result.Responses {
+		ev.found = append(ev.found, discovery{url: pr.URL, level: t.level + 1, captured: pr})
+	}
+	for _, reqURL := range result.AllRequests {
+		ev.found = append(ev.found, discovery{url: reqURL, level: t.level + 1, request: true})
+	}
+	return ev
+}
+
+// fetch 是请求阶段：获取响应内容（优先复用浏览器捕获的响应），从中提取链接和懒加载 chunk。
+// 返回的响应交给匹配阶段，不在扫描范围内的链接不会被请求，此时返回 nil。
+func fetch(t *task, opts Options) (*parser.ParseResult, event) {
+	ev := event{task: t}
+
+	pr := t.captured
+	t.captured = nil
+	if pr == nil {
+		if !opts.Scope.Allow(t.url) {
+			return nil, ev
+		}
+		pr = opts.Client.Fetch(t.url)
+	}
+	if pr.Error != nil {
+		return pr, ev
+	}
+
+	// 查找所有链接，相对链接以当前 URL（或页面中的 <base href>）为基准解析为绝对链接
+	if t.crawl {
+		for _, link := range crawler.ExtractLinks(pr.Body, pr.URL) {
+			ev.found = append(ev.found, discovery{url: link, level: t.level + 1})
+		}
+	}
+	// 从 webpack / Vite / Next.js 的运行时清单中还原懒加载 chunk 的 URL
+	if t.chunkRound < crawler.MaxChunkRounds && (utils.IsScriptURL(pr.URL) || utils.IsPageURL(pr.URL)) {
+		for _, chunkURL := range crawler.DiscoverChunks(pr.Body, pr.URL) {
+			ev.found = append(ev.found, discovery{url: chunkURL, level: t.level + 1, chunkRound: t.chunkRound + 1})
+		}
+	}
+	return pr, ev
+}
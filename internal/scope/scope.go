@@ -0,0 +1,221 @@
+package scope
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"SecureJS/config"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Scope 决定一个链接是否在扫描范围内，爬取、二次请求和匹配各阶段都会用它过滤链接。
+// include / exclude 只用于筛选收集和报告的链接，浏览器和二次请求只按主机范围拦截（见 AllowHost）。
+// nil 的 *Scope 表示不做任何限制。
+type Scope struct {
+	anyHost    bool                // hosts 中配置了 "*"，不限制主机
+	hosts      map[string]struct{} // 精确匹配的主机
+	wildcards  []string            // *.example.com 形式的通配，保存为 ".example.com"
+	cidrs      []*net.IPNet        // 允许的 IP 段
+	include    []*regexp.Regexp    // 白名单正则
+	exclude    []*regexp.Regexp    // 黑名单正则
+	sameDomain bool                // 是否只允许种子 URL 的注册域名
+	domains    map[string]struct{} // 种子 URL 的注册域名（eTLD+1）
+	seeds      map[string]struct{} // 种子 URL（去掉末尾 /），不受 include / exclude 约束
+}
+
+// New 根据配置和种子 URL 构建 Scope。
+// 种子 URL 的主机始终在范围内，其他主机需要显式配置，hosts 中的 "*" 表示允许任意主机；
+// same_domain 为 true 且未配置 hosts / cidrs 时允许种子 URL 注册域名下的所有主机。
+// 没有任何可用的种子主机且未配置主机时（如只扫描本地文件或导入的流量）不限制主机。
+func New(cfg config.Scope, seeds []string) (*Scope, error) {
+	s := &Scope{
+		hosts:      make(map[string]struct{}),
+		sameDomain: cfg.SameDomain,
+		domains:    make(map[string]struct{}),
+		seeds:      make(map[string]struct{}),
+	}
+
+	for _, h := range cfg.Hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		if h == "*" {
+			s.anyHost = true
+			continue
+		}
+		// CIDR 也允许直接写在 hosts 里
+		if strings.Contains(h, "/") {
+			cfg.CIDRs = append(cfg.CIDRs, h)
+			continue
+		}
+		if strings.HasPrefix(h, "*.") {
+			s.wildcards = append(s.wildcards, h[1:])
+			continue
+		}
+		s.hosts[h] = struct{}{}
+	}
+
+	for _, c := range cfg.CIDRs {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(c))
+		if err != nil {
+			return nil, fmt.Errorf("invalid scope cidr '%s': %w", c, err)
+		}
+		s.cidrs = append(s.cidrs, ipNet)
+	}
+
+	for _, expr := range cfg.Include {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scope include regex '%s': %w", expr, err)
+		}
+		s.include = append(s.include, re)
+	}
+	for _, expr := range cfg.Exclude {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scope exclude regex '%s': %w", expr, err)
+		}
+		s.exclude = append(s.exclude, re)
+	}
+
+	// 种子 URL 的主机和注册域名
+	configured := len(s.hosts) > 0 || len(s.wildcards) > 0 || len(s.cidrs) > 0
+	for _, seed := range seeds {
+		s.seeds[seedKey(seed)] = struct{}{}
+		host := hostOf(seed)
+		if host == "" {
+			continue
+		}
+		s.hosts[host] = struct{}{}
+		s.domains[registrableDomain(host)] = struct{}{}
+	}
+	if s.sameDomain && !configured {
+		for domain := range s.domains {
+			if net.ParseIP(domain) == nil {
+				s.wildcards = append(s.wildcards, "."+domain)
+			}
+		}
+	}
+	if len(s.hosts) == 0 && len(s.wildcards) == 0 && len(s.cidrs) == 0 {
+		s.anyHost = true
+	}
+
+	return s, nil
+}
+
+// Allow 判断链接是否在扫描范围内。种子 URL 是明确指定的目标，只检查主机，不受 include / exclude 约束
+func (s *Scope) Allow(rawURL string) bool {
+	if s == nil {
+		return true
+	}
+	if _, ok := s.seeds[seedKey(rawURL)]; !ok && !s.matchRegexps(rawURL) {
+		return false
+	}
+	return s.AllowHost(rawURL)
+}
+
+// AllowHost 只按主机（hosts、cidrs、same_domain）判断链接是否在范围内，忽略 include / exclude。
+// 浏览器加载页面和二次请求（包括 source map 和重定向）用它拦截范围外的主机
+func (s *Scope) AllowHost(rawURL string) bool {
+	if s == nil {
+		return true
+	}
+
+	host := hostOf(rawURL)
+	if host == "" {
+		// 无法解析出主机（如 file:// 或相对链接）时不限制主机
+		return true
+	}
+
+	if s.sameDomain {
+		if _, ok := s.domains[registrableDomain(host)]; !ok {
+			return false
+		}
+	}
+
+	return s.anyHost || s.matchHost(host)
+}
+
+// matchRegexps 判断链接是否满足 include / exclude
+func (s *Scope) matchRegexps(rawURL string) bool {
+	for _, re := range s.exclude {
+		if re.MatchString(rawURL) {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, re := range s.include {
+		if re.MatchString(rawURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter 返回 urls 中在范围内的链接
+func (s *Scope) Filter(urls []string) []string {
+	if s == nil {
+		return urls
+	}
+	filtered := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if s.Allow(u) {
+			filtered = append(filtered, u)
+		}
+	}
+	return filtered
+}
+
+// matchHost 依次检查精确主机、通配子域名和 IP 段
+func (s *Scope) matchHost(host string) bool {
+	if _, ok := s.hosts[host]; ok {
+		return true
+	}
+	for _, suffix := range s.wildcards {
+		// *.example.com 同时匹配 example.com 本身
+		if strings.HasSuffix(host, suffix) || host == suffix[1:] {
+			return true
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		for _, ipNet := range s.cidrs {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hostOf 返回链接中小写的主机名（不含端口），解析失败返回空字符串
+func hostOf(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// seedKey 返回比较种子 URL 时使用的形式，去掉首尾空白和末尾的 /
+func seedKey(rawURL string) string {
+	return strings.TrimSuffix(strings.TrimSpace(rawURL), "/")
+}
+
+// registrableDomain 返回主机的注册域名（eTLD+1），IP 或无法识别的主机原样返回
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
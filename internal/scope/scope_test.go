@@ -0,0 +1,108 @@
+package scope
+
+import (
+	"testing"
+
+	"SecureJS/config"
+)
+
+func TestAllow(t *testing.T) {
+	seeds := []string{"https://www.example.com/app"}
+	tests := []struct {
+		name  string
+		cfg   config.Scope
+		seeds []string
+		url   string
+		want  bool
+	}{
+		{"seed host", config.Scope{}, seeds, "https://www.example.com/main.js", true},
+		{"seed host with port", config.Scope{}, seeds, "https://www.example.com:8443/main.js", true},
+		{"other host by default", config.Scope{}, seeds, "https://cdn.jsdelivr.net/npm/vue.js", false},
+		{"sibling subdomain by default", config.Scope{}, seeds, "https://api.example.com/v1", false},
+		{"relative link", config.Scope{}, seeds, "/static/app.js", true},
+		{"any host", config.Scope{Hosts: []string{"*"}}, seeds, "https://cdn.jsdelivr.net/npm/vue.js", true},
+		{"exact host", config.Scope{Hosts: []string{"API.example.com"}}, seeds, "https://api.example.com/v1", true},
+		{"seed host kept with hosts", config.Scope{Hosts: []string{"api.example.com"}}, seeds, "https://www.example.com/", true},
+		{"wildcard subdomain", config.Scope{Hosts: []string{"*.example.org"}}, seeds, "https://a.b.example.org/", true},
+		{"wildcard apex", config.Scope{Hosts: []string{"*.example.org"}}, seeds, "https://example.org/", true},
+		{"wildcard suffix only", config.Scope{Hosts: []string{"*.example.org"}}, seeds, "https://badexample.org/", false},
+		{"cidr", config.Scope{CIDRs: []string{"10.0.0.0/8"}}, seeds, "http://10.1.2.3:8080/", true},
+		{"cidr in hosts", config.Scope{Hosts: []string{"192.168.0.0/16"}}, seeds, "http://192.168.1.1/", true},
+		{"outside cidr", config.Scope{CIDRs: []string{"10.0.0.0/8"}}, seeds, "http://11.0.0.1/", false},
+		{"same domain subdomain", config.Scope{SameDomain: true}, seeds, "https://api.example.com/v1", true},
+		{"same domain other domain", config.Scope{SameDomain: true}, seeds, "https://example.net/", false},
+		{"same domain with hosts", config.Scope{SameDomain: true, Hosts: []string{"api.example.com"}}, seeds, "https://img.example.com/", false},
+		{"same domain any host", config.Scope{SameDomain: true, Hosts: []string{"*"}}, seeds, "https://example.net/", false},
+		{"include miss", config.Scope{Include: []string{`/api/`}}, seeds, "https://www.example.com/static/app.js", false},
+		{"include hit", config.Scope{Include: []string{`/api/`}}, seeds, "https://www.example.com/api/user", true},
+		{"exclude", config.Scope{Exclude: []string{`(?i)logout`}}, seeds, "https://www.example.com/Logout", false},
+		{"seed ignores include", config.Scope{Include: []string{`\.js$`}}, seeds, "https://www.example.com/app", true},
+		{"seed with trailing slash ignores include", config.Scope{Include: []string{`/api/`}}, seeds, "https://www.example.com/app/", true},
+		{"seed ignores exclude", config.Scope{Exclude: []string{`/app`}}, seeds, "https://www.example.com/app", true},
+		{"non-seed page follows include", config.Scope{Include: []string{`\.js$`}}, seeds, "https://www.example.com/about", false},
+		{"no seeds", config.Scope{}, nil, "https://cdn.jsdelivr.net/npm/vue.js", true},
+		{"no seeds with hosts", config.Scope{Hosts: []string{"example.com"}}, nil, "https://cdn.jsdelivr.net/npm/vue.js", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.cfg, tt.seeds)
+			if err != nil {
+				t.Fatalf("New() error: %v", err)
+			}
+			if got := s.Allow(tt.url); got != tt.want {
+				t.Errorf("Allow(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowHost(t *testing.T) {
+	seeds := []string{"https://www.example.com/app"}
+	cfg := config.Scope{Include: []string{`/api/`}, Exclude: []string{`(?i)logout`}}
+	s, err := New(cfg, seeds)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		// include / exclude 不影响浏览器和二次请求的拦截
+		{"https://www.example.com/static/main.js", true},
+		{"https://www.example.com/logout", true},
+		{"https://www.example.com/static/main.js.map", true},
+		{"https://cdn.jsdelivr.net/npm/vue.js", false},
+		{"data:text/plain,abc", true},
+	}
+	for _, tt := range tests {
+		if got := s.AllowHost(tt.url); got != tt.want {
+			t.Errorf("AllowHost(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestAllowNil(t *testing.T) {
+	var s *Scope
+	if !s.Allow("https://cdn.jsdelivr.net/npm/vue.js") {
+		t.Error("nil Scope should allow every link")
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Scope
+	}{
+		{"cidr", config.Scope{CIDRs: []string{"10.0.0.0/33"}}},
+		{"include", config.Scope{Include: []string{"("}}},
+		{"exclude", config.Scope{Exclude: []string{"["}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg, nil); err == nil {
+				t.Error("New() should fail")
+			}
+		})
+	}
+}
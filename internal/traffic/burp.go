@@ -0,0 +1,97 @@
+package traffic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"SecureJS/internal/parser"
+)
+
+// burpItems 是 Burp Suite "Save items" 导出的 XML 中我们关心的字段
+type burpItems struct {
+	Items []burpItem `xml:"item"`
+}
+
+type burpItem struct {
+	URL      string      `xml:"url"`
+	Response burpMessage `xml:"response"`
+}
+
+// burpMessage 是原始 HTTP 报文，base64 为 true 时内容经过 base64 编码
+type burpMessage struct {
+	Base64 bool   `xml:"base64,attr"`
+	Data   string `xml:",chardata"`
+}
+
+// loadBurp 读取 Burp Suite Proxy / Target 中 "Save items" 导出的 XML 文件
+func loadBurp(file string, maxSize int64) ([]entry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var items burpItems
+	dec := xml.NewDecoder(f)
+	dec.Strict = false
+	if err := dec.Decode(&items); err != nil {
+		return nil, fmt.Errorf("invalid Burp XML: %w", err)
+	}
+
+	entries := make([]entry, 0, len(items.Items))
+	for _, item := range items.Items {
+		u := strings.TrimSpace(item.URL)
+		if u == "" {
+			continue
+		}
+		e := entry{url: u}
+		if raw := item.Response.bytes(); len(raw) > 0 {
+			pr, err := burpResult(u, raw, maxSize)
+			if err != nil {
+				pr = &parser.ParseResult{URL: u, Error: fmt.Errorf("invalid recorded response: %w", err)}
+			}
+			e.result = pr
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// bytes 返回解码后的原始报文
+func (m burpMessage) bytes() []byte {
+	if !m.Base64 {
+		return []byte(m.Data)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(m.Data))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// burpResult 解析原始 HTTP 响应报文并转换为 ParseResult，分块传输由 http.ReadResponse 处理，
+// Content-Encoding 和字符集与二次请求一样由 parser.FromBytes 处理。
+func burpResult(u string, raw []byte, maxSize int64) (*parser.ParseResult, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 记录的报文可能不完整（Content-Length 与实际长度不符），读到多少算多少
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+
+	pr := parser.FromBytes(u, resp.Header, body, maxSize)
+	pr.StatusCode = resp.StatusCode
+	return pr, nil
+}
@@ -0,0 +1,90 @@
+package traffic
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"SecureJS/internal/parser"
+)
+
+// harLog 是 HAR 1.2 中我们关心的字段
+type harLog struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		URL string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int         `json:"status"`
+		Headers []harHeader `json:"headers"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// loadHAR 读取浏览器开发者工具、Burp、ZAP 等导出的 HAR 文件
+func loadHAR(file string, maxSize int64) ([]entry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var har harLog
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid HAR: %w", err)
+	}
+
+	entries := make([]entry, 0, len(har.Log.Entries))
+	for _, he := range har.Log.Entries {
+		if he.Request.URL == "" {
+			continue
+		}
+		e := entry{url: he.Request.URL}
+		// 状态码为 0 表示请求没有得到响应（被取消、被拦截等）
+		if he.Response.Status > 0 && he.Response.Content.Text != "" {
+			e.result = harResult(he, maxSize)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// harResult 将 HAR 中记录的响应转换为 ParseResult。
+// content.text 已经解压，未使用 base64 时也已经解码为 Unicode，所以解码时忽略 Content-Encoding。
+func harResult(he harEntry, maxSize int64) *parser.ParseResult {
+	header := make(http.Header)
+	for _, h := range he.Response.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	if header.Get("Content-Type") == "" && he.Response.Content.MimeType != "" {
+		header.Set("Content-Type", he.Response.Content.MimeType)
+	}
+
+	body := []byte(he.Response.Content.Text)
+	if he.Response.Content.Encoding == "base64" {
+		if decoded, err := base64.StdEncoding.DecodeString(he.Response.Content.Text); err == nil {
+			body = decoded
+		}
+	}
+
+	decodeHeader := header.Clone()
+	decodeHeader.Del("Content-Encoding")
+	pr := parser.FromBytes(he.Request.URL, decodeHeader, body, maxSize)
+	pr.StatusCode = he.Response.Status
+	pr.Header = header
+	return pr
+}
@@ -0,0 +1,75 @@
+package traffic
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+
+	"SecureJS/internal/matcher"
+	"SecureJS/internal/parser"
+)
+
+// entry 是流量记录中的一条请求及其响应
+type entry struct {
+	url    string
+	result *parser.ParseResult // 没有记录响应内容时为 nil
+}
+
+// Run 导入 HAR 文件和 Burp Suite 导出的 XML 文件中记录的流量并匹配，不启动浏览器也不发起请求。
+// 有响应内容的请求转换为 ParseResult 匹配 URL、响应头和响应内容；没有响应内容的请求只匹配 URL。
+// 同一 URL 出现多次时只保留第一条有响应内容的记录。超过 maxSize 的响应内容会被截断，
+// 匹配结果通过 emit 输出，emit 会被多个 goroutine 并发调用。
+func Run(harFiles []string, burpFiles []string, maxSize int64, m *matcher.Matcher, emit func(*matcher.MatchResult)) {
+	var (
+		entries []entry
+		index   = make(map[string]int)
+		failed  []*parser.ParseResult
+	)
+	add := func(file string, loaded []entry, err error) {
+		if err != nil {
+			failed = append(failed, &parser.ParseResult{URL: file, Error: fmt.Errorf("failed to import %s: %w", file, err)})
+			return
+		}
+		for _, e := range loaded {
+			i, exists := index[e.url]
+			if !exists {
+				index[e.url] = len(entries)
+				entries = append(entries, e)
+			} else if entries[i].result == nil && e.result != nil {
+				entries[i] = e
+			}
+		}
+	}
+	for _, f := range harFiles {
+		loaded, err := loadHAR(f, maxSize)
+		add(f, loaded, err)
+	}
+	for _, f := range burpFiles {
+		loaded, err := loadBurp(f, maxSize)
+		add(f, loaded, err)
+	}
+
+	docs := make(chan *parser.ParseResult, runtime.NumCPU())
+	matched := make(chan struct{})
+	go func() {
+		m.MatchStream(docs, runtime.NumCPU(), emit)
+		close(matched)
+	}()
+
+	responses := 0
+	for _, pr := range failed {
+		docs <- pr
+	}
+	for _, e := range entries {
+		if e.result != nil {
+			responses++
+			docs <- e.result
+		} else {
+			docs <- &parser.ParseResult{URL: e.url}
+		}
+	}
+	close(docs)
+	<-matched
+
+	log.Printf("[*] Imported %d URL(s) from recorded traffic, %d with response", len(entries), responses)
+}
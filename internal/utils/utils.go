@@ -100,4 +100,48 @@ func HasSkipExtension(reqURL string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// pageExtensions 是会被视为"页面"的后缀(小写)
+var pageExtensions = map[string]bool{
+	".html": true, ".htm": true, ".shtml": true, ".xhtml": true,
+	".php": true, ".asp": true, ".aspx": true, ".jsp": true,
+	".do": true, ".action": true,
+}
+
+// IsPageURL 判断链接是否指向一个可以继续递归爬取的 HTML 页面，没有后缀的路径也视为页面
+func IsPageURL(reqURL string) bool {
+	ext := urlExtension(reqURL)
+	return ext == "" || pageExtensions[ext]
+}
+
+// IsScriptURL 判断链接是否指向一个 JS 文件
+func IsScriptURL(reqURL string) bool {
+	return urlExtension(reqURL) == ".js"
+}
+
+// urlExtension 返回 URL 路径中最后一段文件名的后缀(小写)，没有后缀时返回空字符串
+func urlExtension(reqURL string) string {
+	beforeQuery := strings.Split(reqURL, "?")[0]
+	beforeHash := strings.Split(beforeQuery, "#")[0]
+
+	// 去掉协议和主机部分，避免把 example.com 当成后缀
+	if idx := strings.Index(beforeHash, "://"); idx != -1 {
+		rest := beforeHash[idx+3:]
+		slash := strings.Index(rest, "/")
+		if slash == -1 {
+			return ""
+		}
+		beforeHash = rest[slash:]
+	}
+
+	filename := beforeHash
+	if slashIndex := strings.LastIndex(beforeHash, "/"); slashIndex != -1 {
+		filename = beforeHash[slashIndex+1:]
+	}
+	dot := strings.LastIndex(filename, ".")
+	if dot == -1 {
+		return ""
+	}
+	return strings.ToLower(filename[dot:])
+}